@@ -0,0 +1,181 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/halfdane/ilias/internal/checker"
+	"github.com/halfdane/ilias/internal/config"
+	"github.com/halfdane/ilias/internal/evaluator"
+	"github.com/halfdane/ilias/internal/report"
+	"github.com/halfdane/ilias/internal/runner"
+	"github.com/halfdane/ilias/internal/state"
+)
+
+// CheckOptions holds the parsed flags for the check command.
+type CheckOptions struct {
+	ConfigPath  string
+	Concurrency int
+	Verbose     bool
+	SlotPath    string // optional "Group/Tile/slot" to check and trace on its own
+	StateDir    string
+	Deadline    time.Duration
+}
+
+func runCheck(args []string) error {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+
+	opts := CheckOptions{}
+	fs.StringVar(&opts.ConfigPath, "c", "config.yaml", "Path to config file")
+	fs.StringVar(&opts.ConfigPath, "config", "config.yaml", "Path to config file")
+	fs.IntVar(&opts.Concurrency, "concurrency", 0, "Max parallel checks (0 = auto)")
+	fs.BoolVar(&opts.Verbose, "v", false, "Verbose logging to stderr")
+	fs.BoolVar(&opts.Verbose, "verbose", false, "Verbose logging to stderr")
+	fs.StringVar(&opts.StateDir, "state-dir", state.DefaultDir(), "Directory where silences and cached results are kept")
+	fs.DurationVar(&opts.Deadline, "deadline", 0, "Stop unfinished checks after this long and mark them as timed out (0 = no deadline)")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	switch fs.NArg() {
+	case 0:
+	case 1:
+		opts.SlotPath = fs.Arg(0)
+	default:
+		return fmt.Errorf("check takes at most one slot path, got %d arguments", fs.NArg())
+	}
+
+	if opts.SlotPath != "" {
+		return checkSlot(opts, os.Stdout)
+	}
+
+	cfg, err := config.Load(opts.ConfigPath)
+	if err != nil {
+		return pluginError(err)
+	}
+
+	var logger io.Writer = io.Discard
+	if opts.Verbose {
+		logger = os.Stderr
+	}
+
+	ctx, cancel := runContext(opts.Deadline, os.Stderr)
+	defer cancel()
+	result, err := runner.Run(ctx, cfg, runner.Options{
+		Concurrency: opts.Concurrency,
+		Verbose:     opts.Verbose,
+		Logger:      logger,
+		StateDir:    opts.StateDir,
+	})
+	if err != nil {
+		return pluginError(fmt.Errorf("running checks: %w", err))
+	}
+	warnings(result, opts.Verbose, os.Stderr)
+	interrupted(ctx, opts.Deadline, os.Stderr)
+
+	return pluginResult(cfg, result)
+}
+
+// checkSlot runs a single slot's check and prints the result together with a
+// rule-by-rule trace of the evaluation. The exit status is the plugin level of
+// the resulting status.
+func checkSlot(opts CheckOptions, w io.Writer) error {
+	cfg, err := config.Load(opts.ConfigPath)
+	if err != nil {
+		return err
+	}
+
+	_, _, slot, ok := cfg.FindSlot(opts.SlotPath)
+	if !ok {
+		return fmt.Errorf("no slot %q in %s (use \"Group/Tile/slot\")", opts.SlotPath, opts.ConfigPath)
+	}
+
+	chk, err := checker.NewChecker(slot.Check)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := runContext(opts.Deadline, os.Stderr)
+	defer cancel()
+	result := chk.Check(ctx)
+	interrupted(ctx, opts.Deadline, os.Stderr)
+
+	// The rules see the real output; only what is printed is redacted.
+	fmt.Fprintf(w, "Slot:     %s\n", opts.SlotPath)
+	fmt.Fprintf(w, "Check:    %s %s\n", slot.Check.Type, cfg.Redact(slot.Check.TargetString()))
+	fmt.Fprintf(w, "Code:     %d\n", result.Code)
+	fmt.Fprintf(w, "Duration: %s\n", result.Duration.Round(time.Microsecond))
+	if result.Err != nil {
+		fmt.Fprintf(w, "Error:    %s\n", cfg.Redact(result.Err.Error()))
+	}
+	fmt.Fprintln(w, "Output:")
+	if result.Output == "" {
+		fmt.Fprintln(w, "  (empty)")
+	} else {
+		for _, line := range strings.Split(cfg.Redact(result.Output), "\n") {
+			fmt.Fprintf(w, "  %s\n", line)
+		}
+	}
+
+	status, traces := evaluator.Explain(result, slot.Rules)
+	fmt.Fprintln(w)
+	if slot.RulesFrom != "" {
+		fmt.Fprintf(w, "Rules (from %s):\n", slot.RulesFrom)
+	} else {
+		fmt.Fprintln(w, "Rules:")
+	}
+	for _, tr := range traces {
+		mark := "✗"
+		if tr.Matched {
+			mark = "✓"
+		}
+		fmt.Fprintf(w, "  %s rule[%d] → %s %s\n", mark, tr.Index, tr.Rule.Status.ID, tr.Rule.Status.Label)
+		if len(tr.Conditions) == 0 {
+			fmt.Fprintln(w, "      match: {} (catch-all)")
+		}
+		for _, c := range tr.Conditions {
+			if c.Matched {
+				fmt.Fprintf(w, "      %s %q: matched\n", c.Field, c.Pattern)
+			} else {
+				fmt.Fprintf(w, "      %s %q: failed, %s\n", c.Field, c.Pattern, c.Reason)
+			}
+		}
+	}
+	if n := len(slot.Rules) - len(traces); n > 0 {
+		fmt.Fprintf(w, "  (%d later rule(s) not evaluated)\n", n)
+	}
+
+	fmt.Fprintln(w)
+	last := len(traces) - 1
+	if last >= 0 && traces[last].Matched {
+		fmt.Fprintf(w, "Status:   %s %s (rule[%d])\n", status.ID, status.Label, traces[last].Index)
+	} else {
+		fmt.Fprintf(w, "Status:   %s %s (no rule matched, built-in error status)\n", status.ID, status.Label)
+	}
+
+	if level := cfg.Severity.Level(status.ID); level != config.LevelOK {
+		return exitStatus(level)
+	}
+	return nil
+}
+
+// pluginResult prints a monitoring-plugin summary for the result to stdout and
+// returns the matching exitStatus, or nil when everything is OK.
+func pluginResult(cfg *config.Config, result *runner.DashboardResult) error {
+	summary := report.Summarize(result, cfg.Severity)
+	fmt.Println(summary)
+	if code := summary.ExitCode(); code != 0 {
+		return exitStatus(code)
+	}
+	return nil
+}
+
+// pluginError reports a failure that prevented checks from running as
+// UNKNOWN, the plugin convention for errors of the plugin itself.
+func pluginError(err error) error {
+	fmt.Printf("ILIAS %s - %v\n", config.LevelUnknown, err)
+	return exitStatus(config.LevelUnknown)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckSlot_Trace(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	yaml := `
+title: Test
+defaults:
+  rules:
+    - match: { code: 0 }
+      status: { id: ok, label: "✅" }
+    - match: {}
+      status: { id: down, label: "🔴" }
+groups:
+  - name: Network
+    tiles:
+      - name: Gateway
+        slots:
+          - name: ping
+            check: "echo unreachable; exit 1"
+`
+	if err := os.WriteFile(cfgPath, []byte(yaml), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	err := checkSlot(CheckOptions{ConfigPath: cfgPath, SlotPath: "Network/Gateway/ping"}, &buf)
+
+	var es exitStatus
+	if !errors.As(err, &es) || es != 2 {
+		t.Fatalf("err = %v, want exit status 2 (down is CRITICAL)", err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{
+		"Code:     1",
+		"unreachable",
+		"Rules (from defaults):",
+		`✗ rule[0] → ok ✅`,
+		`code "0": failed, got 1`,
+		`✓ rule[1] → down 🔴`,
+		"match: {} (catch-all)",
+		"Status:   down 🔴 (rule[1])",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestCheckSlot_SecretInOutput(t *testing.T) {
+	t.Setenv("ILIAS_TEST_HOST", "myhost")
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+	yaml := `
+title: Test
+groups:
+  - name: G
+    tiles:
+      - name: T
+        slots:
+          - name: s
+            check: 'echo "${secret:ILIAS_TEST_HOST} is healthy"'
+            rules:
+              - match: { output: "^myhost is healthy" }
+                status: { id: ok, label: "✅" }
+              - match: {}
+                status: { id: down, label: "🔴" }
+`
+	if err := os.WriteFile(cfgPath, []byte(yaml), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := checkSlot(CheckOptions{ConfigPath: cfgPath, SlotPath: "G/T/s"}, &buf); err != nil {
+		t.Fatalf("err = %v, want ok: the rules must see the real output", err)
+	}
+	if out := buf.String(); !strings.Contains(out, "  *** is healthy\n") || strings.Contains(out, "  myhost is healthy\n") {
+		t.Errorf("output = %q, want the secret redacted", out)
+	}
+}
+
+func TestCheckSlot_UnknownSlot(t *testing.T) {
+	cfgPath := filepath.Join("..", "..", "testdata", "basic.yaml")
+
+	err := checkSlot(CheckOptions{ConfigPath: cfgPath, SlotPath: "Services/Nope/status"}, &bytes.Buffer{})
+	if err == nil || !strings.Contains(err.Error(), `no slot "Services/Nope/status"`) {
+		t.Errorf("err = %v, want no-slot error", err)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/halfdane/ilias/internal/config"
+	"github.com/halfdane/ilias/internal/fixture"
+)
+
+// TestOptions holds the parsed flags for the test command.
+type TestOptions struct {
+	ConfigPath   string
+	Verbose      bool
+	FixturePaths []string
+}
+
+func runTest(args []string) error {
+	fs := flag.NewFlagSet("test", flag.ExitOnError)
+
+	opts := TestOptions{}
+	fs.StringVar(&opts.ConfigPath, "c", "config.yaml", "Path to config file")
+	fs.StringVar(&opts.ConfigPath, "config", "config.yaml", "Path to config file")
+	fs.BoolVar(&opts.Verbose, "v", false, "Also print the totals of each fixture file")
+	fs.BoolVar(&opts.Verbose, "verbose", false, "Also print the totals of each fixture file")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	opts.FixturePaths = fs.Args()
+	if len(opts.FixturePaths) == 0 {
+		return fmt.Errorf("test needs at least one fixture file")
+	}
+
+	return testFixtures(opts, os.Stdout)
+}
+
+// testFixtures evaluates every fixture file against the config and prints
+// each mismatch as a diff of expected and actual status.
+func testFixtures(opts TestOptions, w io.Writer) error {
+	cfg, err := config.Load(opts.ConfigPath)
+	if err != nil {
+		return err
+	}
+
+	passed, failed := 0, 0
+	for _, path := range opts.FixturePaths {
+		suites, err := fixture.Load(path)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+
+		rep := fixture.Run(cfg, suites)
+		passed += rep.Passed
+		failed += len(rep.Failures)
+
+		if opts.Verbose {
+			fmt.Fprintf(w, "%s: %d passed, %d failed\n", path, rep.Passed, len(rep.Failures))
+		}
+		for _, f := range rep.Failures {
+			printFailure(w, path, f)
+		}
+	}
+
+	fmt.Fprintf(w, "%d passed, %d failed\n", passed, failed)
+	if failed > 0 {
+		return exitStatus(1)
+	}
+	return nil
+}
+
+func printFailure(w io.Writer, path string, f fixture.Failure) {
+	if f.Err != nil {
+		fmt.Fprintf(w, "FAIL %s: %v\n\n", path, f.Err)
+		return
+	}
+
+	name := f.Case.Name
+	if name == "" {
+		name = fmt.Sprintf("case[%d]", f.Index)
+	}
+	fmt.Fprintf(w, "FAIL %s: %s: %s\n", path, f.Slot, name)
+	fmt.Fprintf(w, "  given: code=%d output=%q", f.Case.Code, f.Case.Output)
+	if f.Case.Error != "" {
+		fmt.Fprintf(w, " error=%q", f.Case.Error)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "  - expect: %s\n", f.Case.Expect)
+
+	last := len(f.Traces) - 1
+	if last >= 0 && f.Traces[last].Matched {
+		fmt.Fprintf(w, "  + got:    %s %s (rule[%d])\n\n", f.Got.ID, f.Got.Label, f.Traces[last].Index)
+	} else {
+		fmt.Fprintf(w, "  + got:    %s %s (no rule matched)\n\n", f.Got.ID, f.Got.Label)
+	}
+}
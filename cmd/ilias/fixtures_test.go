@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTestFixtures(t *testing.T) {
+	dir := t.TempDir()
+	fixturePath := filepath.Join(dir, "basic.test.yaml")
+	fixtures := `
+"Services/Web Server/status":
+  - { name: healthy, code: 0, output: ok, expect: ok }
+  - { name: timed out, error: "context deadline exceeded", expect: ok }
+`
+	if err := os.WriteFile(fixturePath, []byte(fixtures), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	err := testFixtures(TestOptions{
+		ConfigPath:   filepath.Join("..", "..", "testdata", "basic.yaml"),
+		FixturePaths: []string{fixturePath},
+	}, &buf)
+
+	var es exitStatus
+	if !errors.As(err, &es) || es != 1 {
+		t.Fatalf("err = %v, want exit status 1", err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{
+		"FAIL " + fixturePath + ": Services/Web Server/status: timed out",
+		`error="context deadline exceeded"`,
+		"  - expect: ok",
+		"  + got:    error ❌ (rule[1])",
+		"1 passed, 1 failed",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
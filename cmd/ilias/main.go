@@ -2,15 +2,23 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/halfdane/ilias/internal/checker"
 	"github.com/halfdane/ilias/internal/config"
 	"github.com/halfdane/ilias/internal/renderer"
 	"github.com/halfdane/ilias/internal/runner"
+	"github.com/halfdane/ilias/internal/state"
 )
 
 // version is set at build time via -ldflags "-X main.version=vX.Y.Z".
@@ -23,7 +31,11 @@ Usage:
 
 Commands:
   generate    Run checks and generate the static HTML dashboard
+  check       Run checks and print a monitoring-plugin summary (exit 0/1/2/3)
+  test        Run rule fixtures through the config's rules without executing checks
   validate    Parse and validate the configuration file
+  silence     Mark a group, tile or slot as in maintenance for a while
+  schema      Print a JSON Schema of the configuration file for editors
   version     Print the version and exit
 
 Flags (for generate):
@@ -34,9 +46,38 @@ Flags (for generate):
   -v, --verbose       Verbose logging to stderr
   --no-tooltips       Don't include check output in hover tooltips (recommended for public dashboards)
   --no-timestamp      Omit the "Generated at" timestamp (recommended for public dashboards)
+  --exit-status       Print a monitoring-plugin summary and exit 0/1/2/3 by the worst slot
+  --template DIR      Directory with dashboard.tmpl/style.css overriding the built-in ones
+  --record DIR        Save every slot's check result to DIR
+  --replay DIR        Use results saved with --record instead of running checks
+  --strict            Reject unknown config keys (validate does this by default)
+  --deadline DUR      Stop unfinished checks after DUR, e.g. 2m, and mark them as timed out
+  --state-dir DIR     Where statuses, silences and cached results are kept
+                      (default: $ILIAS_STATE_DIR or ~/.local/state/ilias)
+
+Usage (for check):
+  ilias check [flags]                    Check every slot, print a plugin summary
+  ilias check [flags] "Group/Tile/slot"  Check one slot and trace its rules
+
+Flags (for check):
+  -c, --config        Path to config file (default: ./config.yaml)
+  --concurrency       Max parallel checks (default: auto)
+  -v, --verbose       Verbose logging to stderr
+  --deadline DUR      Stop unfinished checks after DUR and mark them as timed out
+  --state-dir DIR     Where silences and cached results are kept (default: as for generate)
+
+Usage (for test):
+  ilias test [-c config.yaml] [-v] fixtures.yaml...
+
+Usage (for silence):
+  ilias silence add [-c config.yaml] [--state-dir DIR] --for 2h [--reason TEXT] "Group[/Tile[/slot]]"
+  ilias silence list [--state-dir DIR]
 `
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == checker.LimitsArg {
+		checker.ExecLimited(os.Args[2:])
+	}
 	if len(os.Args) < 2 {
 		fmt.Fprint(os.Stderr, usage)
 		os.Exit(1)
@@ -45,13 +86,27 @@ func main() {
 	switch os.Args[1] {
 	case "generate":
 		if err := runGenerate(os.Args[2:]); err != nil {
-			fmt.Fprintf(os.Stderr, "error: %v\n", err)
-			os.Exit(1)
+			exit(err)
+		}
+	case "check":
+		if err := runCheck(os.Args[2:]); err != nil {
+			exit(err)
+		}
+	case "test":
+		if err := runTest(os.Args[2:]); err != nil {
+			exit(err)
 		}
 	case "validate":
 		if err := runValidate(os.Args[2:]); err != nil {
-			fmt.Fprintf(os.Stderr, "error: %v\n", err)
-			os.Exit(1)
+			exit(err)
+		}
+	case "silence":
+		if err := runSilence(os.Args[2:]); err != nil {
+			exit(err)
+		}
+	case "schema":
+		if err := printSchema(os.Stdout); err != nil {
+			exit(err)
 		}
 	case "version", "--version", "-version":
 		fmt.Printf("ilias %s\n", version)
@@ -66,6 +121,25 @@ func main() {
 	}
 }
 
+// exitStatus is returned by commands that have already reported their result
+// and only need the process to exit with a specific code.
+type exitStatus int
+
+func (e exitStatus) Error() string {
+	return fmt.Sprintf("exit status %d", int(e))
+}
+
+// exit terminates the process for a command error. An exitStatus is used as
+// the exit code as-is; any other error is printed and exits with 1.
+func exit(err error) {
+	var es exitStatus
+	if errors.As(err, &es) {
+		os.Exit(int(es))
+	}
+	fmt.Fprintf(os.Stderr, "error: %v\n", err)
+	os.Exit(1)
+}
+
 // GenerateOptions holds the parsed flags for the generate command.
 type GenerateOptions struct {
 	ConfigPath  string
@@ -75,6 +149,13 @@ type GenerateOptions struct {
 	Verbose     bool
 	NoTooltips  bool
 	NoTimestamp bool
+	ExitStatus  bool
+	Template    string
+	Record      string
+	Replay      string
+	Strict      bool
+	StateDir    string
+	Deadline    time.Duration
 }
 
 func runGenerate(args []string) error {
@@ -91,12 +172,50 @@ func runGenerate(args []string) error {
 	fs.BoolVar(&opts.Verbose, "verbose", false, "Verbose logging to stderr")
 	fs.BoolVar(&opts.NoTooltips, "no-tooltips", false, "Don't include check output in hover tooltips")
 	fs.BoolVar(&opts.NoTimestamp, "no-timestamp", false, "Omit the generated-at timestamp")
+	fs.BoolVar(&opts.ExitStatus, "exit-status", false, "Print a monitoring-plugin summary and exit 0/1/2/3 by the worst slot")
+	fs.StringVar(&opts.Template, "template", "", "Directory with dashboard.tmpl/style.css overriding the built-in ones")
+	fs.StringVar(&opts.Record, "record", "", "Save every slot's check result to this directory")
+	fs.StringVar(&opts.Replay, "replay", "", "Use recorded results from this directory instead of running checks")
+	fs.BoolVar(&opts.Strict, "strict", false, "Reject unknown config keys")
+	fs.StringVar(&opts.StateDir, "state-dir", state.DefaultDir(), "Directory where statuses, silences and cached results are kept between runs")
+	fs.DurationVar(&opts.Deadline, "deadline", 0, "Stop unfinished checks after this long and mark them as timed out (0 = no deadline)")
 
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
+	if opts.Record != "" && opts.Replay != "" {
+		return fmt.Errorf("--record and --replay cannot be used together")
+	}
 
-	cfg, err := config.Load(opts.ConfigPath)
+	err := generate(opts)
+	if err != nil && opts.ExitStatus {
+		var es exitStatus
+		if !errors.As(err, &es) {
+			return pluginError(err)
+		}
+	}
+	return err
+}
+
+// printSchema writes the config JSON Schema.
+func printSchema(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(config.Schema())
+}
+
+// loadConfig loads the config at path. In strict mode unknown keys are
+// reported as errors.
+func loadConfig(path string, strict bool) (*config.Config, error) {
+	if strict {
+		return config.LoadStrict(path)
+	}
+	return config.Load(path)
+}
+
+func generate(opts GenerateOptions) error {
+	cfg, err := loadConfig(opts.ConfigPath, opts.Strict)
 	if err != nil {
 		return err
 	}
@@ -113,21 +232,38 @@ func runGenerate(args []string) error {
 		return printDryRun(cfg)
 	}
 
-	// Run all checks
-	result, err := runner.Run(context.Background(), cfg, runner.Options{
+	// Run all checks. On the deadline or an interrupt the page is still
+	// written, with the unfinished slots timed out.
+	ctx, cancel := runContext(opts.Deadline, os.Stderr)
+	defer cancel()
+	result, err := runner.Run(ctx, cfg, runner.Options{
 		Concurrency: opts.Concurrency,
 		Verbose:     opts.Verbose,
 		Logger:      logger,
+		Record:      opts.Record,
+		Replay:      opts.Replay,
+		StateDir:    opts.StateDir,
 	})
 	if err != nil {
 		return fmt.Errorf("running checks: %w", err)
 	}
+	warnings(result, opts.Verbose, os.Stderr)
+	interrupted(ctx, opts.Deadline, os.Stderr)
+	cancel()
+
+	// Replayed results are not news; don't notify or overwrite the state.
+	// An interrupt stops the notifications, which have timeouts of their own.
+	if cfg.Notifies() && opts.Replay == "" {
+		ctx, cancel := interruptible(context.Background(), "stopping the notifications", os.Stderr)
+		notifyChanges(ctx, cfg, result, state.Dir(opts.StateDir), os.Stderr)
+		cancel()
+	}
 
 	// Render HTML
-	configDir := filepath.Dir(opts.ConfigPath)
-	html, err := renderer.Render(result, configDir, version, renderer.Options{
+	html, err := renderer.Render(result, cfg.Dir, version, renderer.Options{
 		NoTooltips:  opts.NoTooltips,
 		NoTimestamp: opts.NoTimestamp,
+		TemplateDir: templateDir(cfg, opts.Template),
 	})
 	if err != nil {
 		return fmt.Errorf("rendering: %w", err)
@@ -142,14 +278,47 @@ func runGenerate(args []string) error {
 		fmt.Fprintf(logger, "wrote %d bytes to %s\n", len(html), opts.OutputPath)
 	}
 
+	if opts.ExitStatus {
+		return pluginResult(cfg, result)
+	}
 	return nil
 }
 
+// warnings prints the warnings of a run on w, unless the verbose log has
+// already shown them.
+func warnings(result *runner.DashboardResult, verbose bool, w io.Writer) {
+	if verbose {
+		return
+	}
+	for _, warning := range result.Warnings {
+		fmt.Fprintf(w, "[warn] %s\n", warning)
+	}
+}
+
+// templateDir returns the custom template directory: the --template flag if
+// given, otherwise the config's template field resolved relative to the
+// config file. An empty result means the embedded template.
+func templateDir(cfg *config.Config, flagValue string) string {
+	if flagValue != "" {
+		return flagValue
+	}
+	if cfg.Template == "" || filepath.IsAbs(cfg.Template) {
+		return cfg.Template
+	}
+	return filepath.Join(cfg.Dir, cfg.Template)
+}
+
 func printDryRun(cfg *config.Config) error {
 	fmt.Fprintf(os.Stderr, "Dashboard: %s (theme: %s)\n\n", cfg.Title, cfg.Theme)
 
 	for _, g := range cfg.Groups {
 		fmt.Fprintf(os.Stderr, "Group: %s\n", g.Name)
+		if g.Discover != nil {
+			fmt.Fprintf(os.Stderr, "  Discover: %s %s\n", g.Discover.Type, cfg.Redact(g.Discover.TargetString()))
+		}
+		for i := range g.Maintenance {
+			fmt.Fprintf(os.Stderr, "  Maintenance: %s\n", &g.Maintenance[i])
+		}
 		for _, t := range g.Tiles {
 			fmt.Fprintf(os.Stderr, "  Tile: %s\n", t.Name)
 			fmt.Fprintf(os.Stderr, "    Icon: %s\n", t.Icon)
@@ -157,16 +326,56 @@ func printDryRun(cfg *config.Config) error {
 				fmt.Fprintf(os.Stderr, "    Link: %s\n", t.Link)
 			}
 			if t.Generate != nil {
-				fmt.Fprintf(os.Stderr, "    Generate: %s (timeout: %s)\n", t.Generate.Command, t.Generate.Timeout.Duration)
+				fmt.Fprintf(os.Stderr, "    Generate: %s (timeout: %s)\n", t.Generate.CommandString(), t.Generate.Timeout.Duration)
+			}
+			for i := range t.Maintenance {
+				fmt.Fprintf(os.Stderr, "    Maintenance: %s\n", &t.Maintenance[i])
 			}
 			for _, s := range t.Slots {
 				fmt.Fprintf(os.Stderr, "    Slot: %s\n", s.Name)
-				fmt.Fprintf(os.Stderr, "      Check: %s %s", s.Check.Type, s.Check.Target)
+				fmt.Fprintf(os.Stderr, "      Check: %s %s", s.Check.Type, cfg.Redact(s.Check.TargetString()))
+				if s.Check.Shell != "" {
+					fmt.Fprintf(os.Stderr, " (shell: %s)", s.Check.Shell)
+				}
 				if s.Check.Timeout.Duration > 0 {
 					fmt.Fprintf(os.Stderr, " (timeout: %s)", s.Check.Timeout.Duration)
 				}
+				if s.Check.Retries > 0 {
+					fmt.Fprintf(os.Stderr, " (retries: %d)", s.Check.Retries)
+				}
+				if s.Check.Interval.Duration > 0 {
+					fmt.Fprintf(os.Stderr, " (every %s)", s.Check.Interval.Duration)
+				}
+				if l := s.Check.Limits; l != nil && l.String() != "" {
+					fmt.Fprintf(os.Stderr, " (limits: %s)", l)
+				}
 				fmt.Fprintln(os.Stderr)
-				fmt.Fprintf(os.Stderr, "      Rules: %d\n", len(s.Rules))
+				if s.RulesFrom != "" {
+					fmt.Fprintf(os.Stderr, "      Rules: %d (from %s)\n", len(s.Rules), s.RulesFrom)
+				} else {
+					fmt.Fprintf(os.Stderr, "      Rules: %d\n", len(s.Rules))
+				}
+				if p := s.Check.Process; len(p.Env) > 0 || p.ClearEnv {
+					names := slices.Sorted(maps.Keys(p.Env))
+					if p.ClearEnv {
+						fmt.Fprintf(os.Stderr, "      Env: %s only\n", strings.Join(append([]string{"PATH"}, names...), ", "))
+					} else {
+						fmt.Fprintf(os.Stderr, "      Env: %s\n", strings.Join(names, ", "))
+					}
+				}
+				if s.Check.Workdir != "" {
+					fmt.Fprintf(os.Stderr, "      Workdir: %s\n", s.Check.Workdir)
+				}
+				if len(s.DependsOn) > 0 {
+					fmt.Fprintf(os.Stderr, "      Depends on: %s\n", strings.Join(s.DependsOn, ", "))
+				}
+				if s.OnChange != "" {
+					fmt.Fprintf(os.Stderr, "      On change: %s", s.OnChange)
+					if s.OnChangeShell != "" {
+						fmt.Fprintf(os.Stderr, " (shell: %s)", s.OnChangeShell)
+					}
+					fmt.Fprintln(os.Stderr)
+				}
 			}
 		}
 		fmt.Fprintln(os.Stderr)
@@ -174,36 +383,3 @@ func printDryRun(cfg *config.Config) error {
 
 	return nil
 }
-
-// ValidateOptions holds the parsed flags for the validate command.
-type ValidateOptions struct {
-	ConfigPath string
-	Verbose    bool
-}
-
-func runValidate(args []string) error {
-	fs := flag.NewFlagSet("validate", flag.ExitOnError)
-
-	opts := ValidateOptions{}
-	fs.StringVar(&opts.ConfigPath, "c", "config.yaml", "Path to config file")
-	fs.StringVar(&opts.ConfigPath, "config", "config.yaml", "Path to config file")
-	fs.BoolVar(&opts.Verbose, "v", false, "Verbose logging to stderr")
-	fs.BoolVar(&opts.Verbose, "verbose", false, "Verbose logging to stderr")
-
-	if err := fs.Parse(args); err != nil {
-		return err
-	}
-
-	cfg, err := config.Load(opts.ConfigPath)
-	if err != nil {
-		return err
-	}
-
-	tileCount := 0
-	for _, g := range cfg.Groups {
-		tileCount += len(g.Tiles)
-	}
-
-	fmt.Fprintf(os.Stderr, "config OK: %d groups, %d tiles\n", len(cfg.Groups), tileCount)
-	return nil
-}
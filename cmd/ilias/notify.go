@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/halfdane/ilias/internal/config"
+	"github.com/halfdane/ilias/internal/notify"
+	"github.com/halfdane/ilias/internal/runner"
+	"github.com/halfdane/ilias/internal/state"
+)
+
+// notifyChanges sends notifications for every slot whose status differs
+// from the previous run, then saves the statuses of this run to dir.
+// Problems are only reported to w: notifications must never fail generate.
+func notifyChanges(ctx context.Context, cfg *config.Config, result *runner.DashboardResult, dir state.Dir, w io.Writer) {
+	prev, err := dir.Statuses()
+	if err != nil {
+		fmt.Fprintf(w, "[warn] %v\n", err)
+	}
+
+	changes := notify.Changes(prev, result, time.Now())
+	n := &notify.Notifier{Config: cfg}
+	if err := n.Send(ctx, changes); err != nil {
+		for _, line := range strings.Split(cfg.Redact(err.Error()), "\n") {
+			fmt.Fprintf(w, "[warn] %s\n", line)
+		}
+	}
+
+	if err := dir.SaveStatuses(notify.Statuses(prev, result)); err != nil {
+		fmt.Fprintf(w, "[warn] %v\n", err)
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/halfdane/ilias/internal/config"
+	"github.com/halfdane/ilias/internal/runner"
+	"github.com/halfdane/ilias/internal/state"
+)
+
+func TestNotifyChanges(t *testing.T) {
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+	}))
+	defer srv.Close()
+
+	dir := state.Dir(t.TempDir())
+	cfg := &config.Config{Notify: &config.Notify{Webhooks: []config.Webhook{{URL: srv.URL, Body: "{{.Slot}}: {{.Old.ID}} -> {{.New.ID}}"}}}}
+	run := func(status string) string {
+		result := &runner.DashboardResult{Groups: []runner.GroupResult{{
+			Name: "G",
+			Tiles: []runner.TileResult{{
+				Name:  "T",
+				Slots: []runner.SlotResult{{Name: "s", Status: config.Status{ID: status}}},
+			}},
+		}}}
+		var log bytes.Buffer
+		notifyChanges(context.Background(), cfg, result, dir, &log)
+		return log.String()
+	}
+
+	run("ok")
+	if len(bodies) != 0 {
+		t.Errorf("first run sent %q, want nothing", bodies)
+	}
+	run("ok")
+	run("down")
+	if len(bodies) != 1 || bodies[0] != "s: ok -> down" {
+		t.Errorf("webhook bodies = %q, want one ok -> down", bodies)
+	}
+
+	srv.Close()
+	if log := run("ok"); !strings.Contains(log, "[warn] notify.webhooks[0] for G/T/s") {
+		t.Errorf("log = %q, want the delivery failure as a warning", log)
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// runContext returns the context for running the checks. It is cancelled
+// on SIGINT or SIGTERM, and after deadline if that is positive. Once it is
+// done, signals are no longer caught, so a second one ends ilias at once.
+func runContext(deadline time.Duration, w io.Writer) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.Background(), context.CancelFunc(func() {})
+	if deadline > 0 {
+		ctx, cancel = context.WithTimeout(ctx, deadline)
+	}
+	ctx, stop := interruptible(ctx, "stopping the checks", w)
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// interruptible returns a context that is cancelled on SIGINT or SIGTERM,
+// telling w that it is stopping what. Once it is done, signals are no
+// longer caught; cancel only returns after that.
+func interruptible(parent context.Context, what string, w io.Writer) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		defer signal.Stop(sig)
+		select {
+		case <-sig:
+			fmt.Fprintf(w, "interrupted: %s; interrupt again to quit at once\n", what)
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, func() {
+		cancel()
+		<-stopped
+	}
+}
+
+// interrupted warns on w if the run ended early, for whatever reason.
+func interrupted(ctx context.Context, deadline time.Duration, w io.Writer) {
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		fmt.Fprintf(w, "[warn] deadline of %s reached: unfinished slots are marked as timed out\n", deadline)
+	case ctx.Err() != nil:
+		fmt.Fprintln(w, "[warn] interrupted: unfinished slots are marked as timed out")
+	}
+}
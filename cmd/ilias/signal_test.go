@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGenerate_Deadline(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(cfgPath, []byte(`title: T
+defaults:
+  rules: [{ match: { code: 0 }, status: { id: ok, label: "✅" } }]
+groups:
+  - name: G
+    tiles:
+      - name: T
+        slots:
+          - { name: fast, check: "echo fast" }
+          - { name: hung, check: "sleep 30" }
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "index.html")
+
+	start := time.Now()
+	err := generate(GenerateOptions{ConfigPath: cfgPath, OutputPath: out, StateDir: filepath.Join(dir, "state"), Deadline: 500 * time.Millisecond, Concurrency: 2})
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	if d := time.Since(start); d > 10*time.Second {
+		t.Errorf("generate took %v, want it to stop at the deadline", d)
+	}
+
+	html, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("page not written: %v", err)
+	}
+	if !strings.Contains(string(html), "⏱️") || !strings.Contains(string(html), "✅") {
+		t.Errorf("page should show the fast slot ok and the hung one timed out")
+	}
+}
+
+func TestCheckSlot_Deadline(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(cfgPath, []byte(`title: T
+groups:
+  - name: G
+    tiles:
+      - name: T
+        slots:
+          - name: hung
+            check: "sleep 30"
+            rules: [{ match: { code: 0 }, status: { id: ok, label: "✅" } }]
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	var buf bytes.Buffer
+	checkSlot(CheckOptions{ConfigPath: cfgPath, SlotPath: "G/T/hung", Deadline: 500 * time.Millisecond}, &buf)
+	if d := time.Since(start); d > 10*time.Second {
+		t.Errorf("check took %v, want it to stop at the deadline", d)
+	}
+	if !strings.Contains(buf.String(), "Code:     -1\n") {
+		t.Errorf("output = %q, want the check killed at the deadline", buf.String())
+	}
+}
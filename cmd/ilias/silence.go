@@ -0,0 +1,114 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/halfdane/ilias/internal/config"
+	"github.com/halfdane/ilias/internal/state"
+)
+
+// SilenceOptions holds the parsed flags for the silence command.
+type SilenceOptions struct {
+	ConfigPath string
+	StateDir   string
+	For        time.Duration
+	Reason     string
+	Target     string // "Group", "Group/Tile" or "Group/Tile/slot"
+}
+
+func runSilence(args []string) error {
+	if len(args) == 0 || (args[0] != "add" && args[0] != "list") {
+		return fmt.Errorf("silence needs a subcommand: add or list")
+	}
+	fs := flag.NewFlagSet("silence "+args[0], flag.ExitOnError)
+
+	opts := SilenceOptions{}
+	fs.StringVar(&opts.StateDir, "state-dir", state.DefaultDir(), "Directory where silences are kept")
+	if args[0] == "list" {
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		return listSilences(state.Dir(opts.StateDir), time.Now(), os.Stdout)
+	}
+
+	fs.StringVar(&opts.ConfigPath, "c", "config.yaml", "Path to config file")
+	fs.StringVar(&opts.ConfigPath, "config", "config.yaml", "Path to config file")
+	fs.DurationVar(&opts.For, "for", 0, "How long the silence lasts, e.g. 2h")
+	fs.StringVar(&opts.Reason, "reason", "", "Shown in the slot output while silenced")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("silence add takes one target, got %d arguments", fs.NArg())
+	}
+	if opts.For <= 0 {
+		return fmt.Errorf("silence add needs a positive --for duration")
+	}
+	opts.Target = fs.Arg(0)
+	return addSilence(opts, time.Now(), os.Stdout)
+}
+
+// addSilence saves a silence for opts.Target after checking that it names a
+// group, tile or slot of the config.
+func addSilence(opts SilenceOptions, now time.Time, w io.Writer) error {
+	cfg, err := config.Load(opts.ConfigPath)
+	if err != nil {
+		return err
+	}
+	if !silenceTarget(cfg, opts.Target) {
+		return fmt.Errorf("%q is not a group, tile or slot of %s", opts.Target, opts.ConfigPath)
+	}
+
+	s := state.Silence{Target: opts.Target, Until: now.Add(opts.For), Reason: opts.Reason}
+	if err := state.Dir(opts.StateDir).AddSilence(s, now); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "silenced %s until %s\n", s.Target, s.Until.Local().Format("2006-01-02 15:04"))
+	return nil
+}
+
+// silenceTarget reports whether target is the path of a group, tile or slot
+// of cfg. Tiles of groups with a discover check are only known at generate
+// time, so any path within such a group is accepted.
+func silenceTarget(cfg *config.Config, target string) bool {
+	for _, g := range cfg.Groups {
+		if target == g.Name || (g.Discover != nil && strings.HasPrefix(target, g.Name+"/")) {
+			return true
+		}
+		for _, t := range g.Tiles {
+			if target == g.Name+"/"+t.Name {
+				return true
+			}
+			for _, s := range t.Slots {
+				if target == config.SlotPath(g.Name, t.Name, s.Name) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// listSilences prints the silences that have not expired yet.
+func listSilences(dir state.Dir, now time.Time, w io.Writer) error {
+	silences, err := dir.Silences(now)
+	if err != nil {
+		return err
+	}
+	if len(silences) == 0 {
+		fmt.Fprintln(w, "no active silences")
+	}
+	for _, s := range silences {
+		fmt.Fprintf(w, "%s until %s", s.Target, s.Until.Local().Format("2006-01-02 15:04"))
+		if s.Reason != "" {
+			fmt.Fprintf(w, ": %s", s.Reason)
+		}
+		fmt.Fprintln(w)
+	}
+	return nil
+}
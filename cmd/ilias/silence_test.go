@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/halfdane/ilias/internal/state"
+)
+
+func TestSilence_AddAndList(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(cfgPath, []byte(`title: T
+defaults:
+  rules: [{ match: {}, status: { id: ok, label: "✅" } }]
+groups:
+  - name: Web
+    tiles: [{ name: API, slots: [{ name: up, check: "true" }] }]
+  - name: Services
+    discover: "echo []"
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	stateDir := state.Dir(filepath.Join(dir, "state"))
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.Local)
+
+	for _, target := range []string{"Web", "Web/API", "Web/API/up", "Services/Anything/up"} {
+		opts := SilenceOptions{ConfigPath: cfgPath, StateDir: string(stateDir), For: time.Hour, Reason: "deploy", Target: target}
+		var out bytes.Buffer
+		if err := addSilence(opts, now, &out); err != nil {
+			t.Fatalf("addSilence(%q): %v", target, err)
+		}
+		if want := "silenced " + target + " until 2024-05-01 13:00\n"; out.String() != want {
+			t.Errorf("output = %q, want %q", out.String(), want)
+		}
+	}
+
+	err := addSilence(SilenceOptions{ConfigPath: cfgPath, StateDir: string(stateDir), For: time.Hour, Target: "Web/Nope"}, now, &bytes.Buffer{})
+	if err == nil || !strings.Contains(err.Error(), `"Web/Nope" is not a group, tile or slot`) {
+		t.Errorf("unknown target: err = %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := listSilences(stateDir, now, &out); err != nil {
+		t.Fatalf("listSilences: %v", err)
+	}
+	if got := strings.Count(out.String(), "until 2024-05-01 13:00: deploy\n"); got != 4 {
+		t.Errorf("list = %q, want 4 silences", out.String())
+	}
+
+	out.Reset()
+	if err := listSilences(stateDir, now.Add(time.Hour), &out); err != nil || out.String() != "no active silences\n" {
+		t.Errorf("list after expiry = %q, %v", out.String(), err)
+	}
+}
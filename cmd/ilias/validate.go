@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/halfdane/ilias/internal/config"
+	"github.com/halfdane/ilias/internal/renderer"
+)
+
+// ValidateOptions holds the parsed flags for the validate command.
+type ValidateOptions struct {
+	ConfigPath string
+	Verbose    bool
+	Template   string
+	JSON       bool
+	Strict     bool
+}
+
+func runValidate(args []string) error {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+
+	opts := ValidateOptions{}
+	fs.StringVar(&opts.ConfigPath, "c", "config.yaml", "Path to config file")
+	fs.StringVar(&opts.ConfigPath, "config", "config.yaml", "Path to config file")
+	fs.BoolVar(&opts.Verbose, "v", false, "Verbose logging to stderr")
+	fs.BoolVar(&opts.Verbose, "verbose", false, "Verbose logging to stderr")
+	fs.StringVar(&opts.Template, "template", "", "Directory with dashboard.tmpl/style.css overriding the built-in ones")
+	fs.BoolVar(&opts.JSON, "json", false, "Print the result as JSON on stdout")
+	fs.BoolVar(&opts.Strict, "strict", true, "Reject unknown config keys (use --strict=false to allow them)")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	return validate(opts, os.Stdout, os.Stderr)
+}
+
+// problem is one entry of the --json output.
+type problem struct {
+	File    string `json:"file,omitempty"`
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+// validate loads the config (and template, if any) and reports every problem
+// found: one "error:" line each on stderr, or a JSON document on stdout with
+// --json. It returns exitStatus(1) if there were problems.
+func validate(opts ValidateOptions, stdout, stderr io.Writer) error {
+	cfg, err := loadConfig(opts.ConfigPath, opts.Strict)
+
+	var problems []problem
+	if err != nil {
+		problems = problemsOf(err)
+	} else if dir := templateDir(cfg, opts.Template); dir != "" {
+		if err := renderer.ValidateTemplate(cfg, dir); err != nil {
+			problems = append(problems, problem{File: dir, Message: fmt.Sprintf("template %s: %v", dir, err)})
+		} else if opts.Verbose {
+			fmt.Fprintf(stderr, "template OK: %s\n", dir)
+		}
+	}
+
+	if opts.JSON {
+		out := struct {
+			Valid  bool      `json:"valid"`
+			Errors []problem `json:"errors"`
+		}{Valid: len(problems) == 0, Errors: problems}
+		if out.Errors == nil {
+			out.Errors = []problem{}
+		}
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(out); err != nil {
+			return err
+		}
+	}
+
+	if len(problems) > 0 {
+		if !opts.JSON {
+			var errs config.Errors
+			if errors.As(err, &errs) {
+				for _, e := range errs {
+					fmt.Fprintf(stderr, "error: %v\n", e)
+				}
+			} else {
+				for _, p := range problems {
+					fmt.Fprintf(stderr, "error: %s\n", p.Message)
+				}
+			}
+		}
+		return exitStatus(1)
+	}
+
+	if !opts.JSON {
+		tileCount := 0
+		for _, g := range cfg.Groups {
+			tileCount += len(g.Tiles)
+		}
+		fmt.Fprintf(stderr, "config OK: %d groups, %d tiles\n", len(cfg.Groups), tileCount)
+	}
+	return nil
+}
+
+// problemsOf splits a config error into its individual problems.
+func problemsOf(err error) []problem {
+	var errs config.Errors
+	if !errors.As(err, &errs) {
+		return []problem{{Message: err.Error()}}
+	}
+	problems := make([]problem, len(errs))
+	for i, e := range errs {
+		problems[i] = problem{File: e.Pos.File, Line: e.Pos.Line, Column: e.Pos.Column, Message: e.Msg}
+	}
+	return problems
+}
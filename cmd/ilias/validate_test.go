@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidate_JSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	bad := `title: T
+groups:
+  - name: G
+    tiles:
+      - name: X
+        slots:
+          - name: a
+            check: { type: ftp, target: x }
+            rules: [{ match: {}, status: { id: ok } }]
+`
+	if err := os.WriteFile(path, []byte(bad), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	err := validate(ValidateOptions{ConfigPath: path, JSON: true}, &stdout, &stderr)
+	var es exitStatus
+	if !errors.As(err, &es) || es != 1 {
+		t.Fatalf("err = %v, want exit status 1", err)
+	}
+
+	var out struct {
+		Valid  bool
+		Errors []problem
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &out); err != nil {
+		t.Fatalf("invalid JSON %q: %v", stdout.String(), err)
+	}
+	if out.Valid || len(out.Errors) != 2 {
+		t.Fatalf("result = %+v, want two errors", out)
+	}
+	if e := out.Errors[0]; e.File != path || e.Line != 8 || e.Column != 20 || !strings.Contains(e.Message, "check.type") {
+		t.Errorf("errors[0] = %+v", e)
+	}
+	if e := out.Errors[1]; e.Line != 9 || !strings.Contains(e.Message, "status.label") {
+		t.Errorf("errors[1] = %+v", e)
+	}
+	if stderr.Len() != 0 {
+		t.Errorf("stderr = %q, want nothing in JSON mode", stderr.String())
+	}
+}
+
+func TestValidate_TextListsEveryProblem(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	err := validate(ValidateOptions{ConfigPath: filepath.Join("..", "..", "testdata", "basic.yaml")}, &stdout, &stderr)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(stderr.String(), "config OK") {
+		t.Errorf("stderr = %q", stderr.String())
+	}
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("theme: nope\ngroups: []\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	stderr.Reset()
+	err = validate(ValidateOptions{ConfigPath: path}, &stdout, &stderr)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	lines := strings.Split(strings.TrimSpace(stderr.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("stderr = %q, want three error lines", stderr.String())
+	}
+	for _, l := range lines {
+		if !strings.HasPrefix(l, "error: config: ") {
+			t.Errorf("line %q lacks error prefix", l)
+		}
+	}
+}
@@ -4,16 +4,31 @@ package checker
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"net/http"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"slices"
 	"strings"
 	"time"
+
+	"github.com/halfdane/ilias/internal/config"
 )
 
 const defaultTimeout = 30 * time.Second
 
+// retryDelay is the pause between attempts of a check with retries.
+const retryDelay = time.Second
+
+// waitDelay is how long a command may keep running, or keep its output
+// open through a process it left behind, after it exited or was killed.
+const waitDelay = 2 * time.Second
+
 // maxOutputSize is the maximum amount of stdout/stderr captured from
 // commands. Prevents unbounded memory usage from chatty processes.
 const maxOutputSize = 1 << 20 // 1 MiB
@@ -23,6 +38,50 @@ type Result struct {
 	Code   int    // HTTP status code or process exit code
 	Output string // response body or stdout
 	Err    error  // non-nil if the check itself failed (timeout, DNS, etc.)
+
+	Duration time.Duration // wall-clock time the check took
+}
+
+// resultJSON is the serialised form of a Result. The error is kept as its
+// message and the duration as a Go duration string.
+type resultJSON struct {
+	Code     int    `json:"code"`
+	Output   string `json:"output,omitempty"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration,omitempty"`
+}
+
+// MarshalJSON encodes the result with its error as a plain message.
+func (r Result) MarshalJSON() ([]byte, error) {
+	rj := resultJSON{Code: r.Code, Output: r.Output}
+	if r.Err != nil {
+		rj.Error = r.Err.Error()
+	}
+	if r.Duration > 0 {
+		rj.Duration = r.Duration.String()
+	}
+	return json.Marshal(rj)
+}
+
+// UnmarshalJSON decodes a result written by MarshalJSON. A recorded error
+// message becomes an opaque error with the same text.
+func (r *Result) UnmarshalJSON(data []byte) error {
+	var rj resultJSON
+	if err := json.Unmarshal(data, &rj); err != nil {
+		return err
+	}
+	*r = Result{Code: rj.Code, Output: rj.Output}
+	if rj.Error != "" {
+		r.Err = errors.New(rj.Error)
+	}
+	if rj.Duration != "" {
+		d, err := time.ParseDuration(rj.Duration)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q: %w", rj.Duration, err)
+		}
+		r.Duration = d
+	}
+	return nil
 }
 
 // Checker executes a check and returns its result.
@@ -30,25 +89,79 @@ type Checker interface {
 	Check(ctx context.Context) Result
 }
 
-// NewChecker creates the appropriate checker based on check type.
-func NewChecker(checkType, target string, timeout time.Duration) (Checker, error) {
+// NewChecker creates the appropriate checker based on check type. Checks
+// with retries are wrapped in a RetryChecker.
+func NewChecker(c config.Check) (Checker, error) {
+	timeout := c.Timeout.Duration
 	if timeout == 0 {
 		timeout = defaultTimeout
 	}
 
-	switch checkType {
+	var chk Checker
+	var failed func(Result) bool
+	switch c.Type {
 	case "http":
-		return &HTTPChecker{URL: target, Timeout: timeout}, nil
+		chk = &HTTPChecker{
+			URL:     c.Target,
+			Method:  c.Method,
+			Headers: c.Headers,
+			Body:    c.Body,
+			Timeout: timeout,
+		}
+		failed = func(r Result) bool { return r.Err != nil || r.Code >= 500 }
 	case "command":
-		return &CommandChecker{Command: target, Timeout: timeout}, nil
+		chk = &CommandChecker{
+			Command: c.Target,
+			Args:    c.Args,
+			Shell:   c.Shell,
+			Timeout: timeout,
+			Limits:  c.Limits,
+			Process: c.Process,
+		}
+		failed = func(r Result) bool { return r.Err != nil || r.Code != 0 }
 	default:
-		return nil, fmt.Errorf("unknown check type: %q", checkType)
+		return nil, fmt.Errorf("unknown check type: %q", c.Type)
 	}
+
+	if c.Retries > 0 {
+		return &RetryChecker{Checker: chk, Retries: c.Retries, Delay: retryDelay, Failed: failed}, nil
+	}
+	return chk, nil
 }
 
-// HTTPChecker performs an HTTP GET request and returns the status code and body.
+// RetryChecker runs a check again while it fails, up to Retries more times,
+// and returns the last attempt. Duration covers all attempts.
+type RetryChecker struct {
+	Checker
+	Retries int
+	Delay   time.Duration     // pause between attempts
+	Failed  func(Result) bool // whether an attempt should be retried
+}
+
+// Check runs the check until it succeeds or the retries are used up.
+func (c *RetryChecker) Check(ctx context.Context) Result {
+	start := time.Now()
+	result := c.Checker.Check(ctx)
+	for attempt := 0; attempt < c.Retries && c.Failed(result); attempt++ {
+		select {
+		case <-ctx.Done():
+			result.Duration = time.Since(start)
+			return result
+		case <-time.After(c.Delay):
+		}
+		result = c.Checker.Check(ctx)
+	}
+	result.Duration = time.Since(start)
+	return result
+}
+
+// HTTPChecker performs an HTTP request (GET unless Method says otherwise)
+// and returns the status code and body.
 type HTTPChecker struct {
 	URL     string
+	Method  string            // defaults to GET
+	Headers map[string]string // extra request headers
+	Body    string            // request body, sent when non-empty
 	Timeout time.Duration
 	// Client is optional; if nil, a default client with the configured timeout is used.
 	Client *http.Client
@@ -56,15 +169,38 @@ type HTTPChecker struct {
 
 // Check performs the HTTP request.
 func (c *HTTPChecker) Check(ctx context.Context) Result {
+	start := time.Now()
+	result := c.check(ctx)
+	result.Duration = time.Since(start)
+	return result
+}
+
+func (c *HTTPChecker) check(ctx context.Context) Result {
 	client := c.Client
 	if client == nil {
 		client = &http.Client{Timeout: c.Timeout}
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.URL, nil)
+	method := c.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	var reqBody io.Reader
+	if c.Body != "" {
+		reqBody = strings.NewReader(c.Body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.URL, reqBody)
 	if err != nil {
 		return Result{Err: fmt.Errorf("creating request: %w", err)}
 	}
+	for k, v := range c.Headers {
+		if strings.EqualFold(k, "Host") {
+			req.Host = v
+			continue
+		}
+		req.Header.Set(k, v)
+	}
 
 	resp, err := client.Do(req)
 	if err != nil {
@@ -94,18 +230,41 @@ func (c *HTTPChecker) Check(ctx context.Context) Result {
 	}
 }
 
-// CommandChecker executes a shell command and returns the exit code and stdout.
+// CommandChecker executes a shell command, or a program with arguments, and
+// returns the exit code and stdout.
 type CommandChecker struct {
 	Command string
+	Args    []string // program and arguments, run without a shell instead of Command
+	Shell   string   // runs Command, default bash
 	Timeout time.Duration
+	Limits  *config.Limits // optional resource limits
+	Process config.Process // environment, working directory and stdin
 }
 
 // Check executes the command.
 func (c *CommandChecker) Check(ctx context.Context) Result {
+	start := time.Now()
+	result := c.check(ctx)
+	result.Duration = time.Since(start)
+	return result
+}
+
+func (c *CommandChecker) check(ctx context.Context) Result {
 	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
 	defer cancel()
 
-	cmd := exec.CommandContext(ctx, "bash", "-c", "set -o pipefail; "+c.Command)
+	var cmd *exec.Cmd
+	if len(c.Args) == 0 {
+		cmd = Shell(ctx, c.Shell, c.Command)
+	} else {
+		cmd = Exec(ctx, c.Args)
+	}
+	if c.Limits != nil {
+		if err := limit(cmd, c.Limits); err != nil {
+			return Result{Code: -1, Err: err}
+		}
+	}
+	Setup(cmd, c.Process)
 
 	stdout := &limitedBuffer{max: maxOutputSize}
 	stderr := &limitedBuffer{max: maxOutputSize}
@@ -113,9 +272,14 @@ func (c *CommandChecker) Check(ctx context.Context) Result {
 	cmd.Stderr = stderr
 
 	err := cmd.Run()
+	KillLeftovers(cmd)
 
 	exitCode := 0
-	if err != nil {
+	if errors.Is(err, exec.ErrWaitDelay) {
+		// The command exited, but something it left behind held its output
+		// open; what the command itself printed and returned still counts.
+		exitCode = cmd.ProcessState.ExitCode()
+	} else if err != nil {
 		if exitErr, ok := err.(*exec.ExitError); ok {
 			exitCode = exitErr.ExitCode()
 		} else {
@@ -145,6 +309,57 @@ func (c *CommandChecker) Check(ctx context.Context) Result {
 	}
 }
 
+// Shell returns the invocation of shell (bash if empty) that runs a command
+// string from the config. Bash runs it with pipefail set, so that a pipeline
+// fails if any part of it does.
+func Shell(ctx context.Context, shell, command string) *exec.Cmd {
+	if shell == "" {
+		shell = "bash"
+	}
+	if filepath.Base(shell) == "bash" {
+		command = "set -o pipefail; " + command
+	}
+	return Exec(ctx, []string{shell, "-c", command})
+}
+
+// Exec returns the invocation of a program with arguments, without a shell.
+// When ctx is done, the program is killed together with everything it
+// started.
+func Exec(ctx context.Context, args []string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	killProcessGroup(cmd)
+	cmd.WaitDelay = waitDelay
+	return cmd
+}
+
+// Setup applies p to cmd. With ClearEnv, only PATH is kept from the
+// environment of ilias, so that programs are still found.
+func Setup(cmd *exec.Cmd, p config.Process) {
+	if len(p.Env) > 0 || p.ClearEnv {
+		env := []string{"PATH=" + os.Getenv("PATH")}
+		if !p.ClearEnv {
+			env = os.Environ()
+		}
+		for _, k := range slices.Sorted(maps.Keys(p.Env)) {
+			env = append(env, k+"="+p.Env[k])
+		}
+		cmd.Env = env
+	}
+	cmd.Dir = p.Workdir
+	if p.Stdin != "" {
+		cmd.Stdin = strings.NewReader(p.Stdin)
+	}
+}
+
+// KillLeftovers kills whatever a command from Shell or Exec left running
+// after it exited, such as a background job. Call it once cmd.Run or
+// cmd.Wait has returned, whatever the error.
+func KillLeftovers(cmd *exec.Cmd) {
+	if cmd.Process != nil {
+		killGroup(cmd)
+	}
+}
+
 // limitedBuffer is an io.Writer that silently discards writes once the
 // buffer exceeds max bytes. This prevents runaway command output from
 // consuming unbounded memory.
@@ -2,13 +2,28 @@ package checker
 
 import (
 	"context"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/halfdane/ilias/internal/config"
 )
 
+// TestMain lets the test binary stand in for ilias when a command is
+// started with limits.
+func TestMain(m *testing.M) {
+	if len(os.Args) > 1 && os.Args[1] == LimitsArg {
+		ExecLimited(os.Args[2:])
+	}
+	os.Exit(m.Run())
+}
+
 func TestHTTPChecker_Success(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -157,19 +172,282 @@ func TestCommandChecker_Timeout(t *testing.T) {
 	}
 }
 
+func TestCommandChecker_TimeoutKillsChildren(t *testing.T) {
+	// The background sleep keeps stdout open: unless it is killed along
+	// with bash, the check waits for it.
+	checker := &CommandChecker{
+		Command: "sleep 30 & wait",
+		Timeout: 100 * time.Millisecond,
+	}
+	result := checker.Check(context.Background())
+
+	if result.Duration > 10*time.Second {
+		t.Errorf("check took %v, want the child killed at the timeout", result.Duration)
+	}
+}
+
+func TestCommandChecker_LeftoverChild(t *testing.T) {
+	// The background subshell keeps stdout open after bash exits; the check
+	// gives up on it after waitDelay instead of waiting for it.
+	checker := &CommandChecker{
+		Command: "(sleep 30; echo late) & echo early",
+		Timeout: 20 * time.Second,
+	}
+	result := checker.Check(context.Background())
+
+	if result.Err != nil {
+		t.Fatalf("unexpected error: %v", result.Err)
+	}
+	if result.Code != 0 || result.Output != "early" {
+		t.Errorf("result = %d %q, want 0 \"early\"", result.Code, result.Output)
+	}
+	if result.Duration > 10*time.Second {
+		t.Errorf("check took %v, want it to stop waiting after %v", result.Duration, waitDelay)
+	}
+}
+
+func TestCommandChecker_LeftoverChildAfterFailure(t *testing.T) {
+	// The background subshell doesn't hold the output open, so the check
+	// returns at once; it must still not outlive the failed command.
+	marker := filepath.Join(t.TempDir(), "marker")
+	checker := &CommandChecker{
+		Command: "(exec >/dev/null 2>&1; sleep 1; touch " + marker + ") & exit 1",
+		Timeout: 5 * time.Second,
+	}
+	result := checker.Check(context.Background())
+	if result.Err != nil || result.Code != 1 {
+		t.Fatalf("result = %d %v, want exit code 1", result.Code, result.Err)
+	}
+
+	time.Sleep(2 * time.Second)
+	if _, err := os.Stat(marker); err == nil {
+		t.Error("the background job kept running after the check")
+	}
+}
+
+func TestCommandChecker_Limits(t *testing.T) {
+	checker := &CommandChecker{
+		Command: "ulimit -t; ulimit -v; ulimit -u",
+		Timeout: 5 * time.Second,
+		Limits: &config.Limits{
+			CPU:       config.Duration{Duration: 1500 * time.Millisecond},
+			Memory:    512 << 20,
+			Processes: 4096,
+		},
+	}
+	result := checker.Check(context.Background())
+
+	if result.Err != nil || result.Code != 0 {
+		t.Fatalf("result = %d %v: %s", result.Code, result.Err, result.Output)
+	}
+	if want := "2\n524288\n4096"; result.Output != want {
+		t.Errorf("output = %q, want %q", result.Output, want)
+	}
+}
+
+func TestCommandChecker_LimitsWithoutBash(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("reads /proc/self/limits")
+	}
+	limits := &config.Limits{Processes: 4096}
+	tests := []struct {
+		name    string
+		checker *CommandChecker
+	}{
+		{"no shell", &CommandChecker{Args: []string{"grep", "Max processes", "/proc/self/limits"}, Limits: limits}},
+		{"sh", &CommandChecker{Command: "grep 'Max processes' /proc/self/limits", Shell: "sh", Limits: limits}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.checker.Timeout = 5 * time.Second
+			result := tt.checker.Check(context.Background())
+
+			if result.Err != nil || result.Code != 0 {
+				t.Fatalf("result = %d %v: %s", result.Code, result.Err, result.Output)
+			}
+			if fields := strings.Fields(result.Output); len(fields) < 4 || fields[2] != "4096" || fields[3] != "4096" {
+				t.Errorf("output = %q, want processes limited to 4096", result.Output)
+			}
+		})
+	}
+}
+
+func TestCommandChecker_Args(t *testing.T) {
+	tests := []struct {
+		name    string
+		checker *CommandChecker
+		want    string
+	}{
+		{
+			name:    "no shell",
+			checker: &CommandChecker{Args: []string{"echo", "$HOME; it's", "*"}},
+			want:    "$HOME; it's *",
+		},
+		{
+			name: "no shell with limits",
+			checker: &CommandChecker{
+				Args:   []string{"sh", "-c", `ulimit -t; echo "$1"`, "sh", "a b"},
+				Limits: &config.Limits{CPU: config.Duration{Duration: 3 * time.Second}},
+			},
+			want: "3\na b",
+		},
+		{
+			name:    "shell",
+			checker: &CommandChecker{Command: `echo "$0"`, Shell: "sh"},
+			want:    "sh",
+		},
+		{
+			name:    "bash sets pipefail",
+			checker: &CommandChecker{Command: "false | true; echo $?"},
+			want:    "1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.checker.Timeout = 5 * time.Second
+			result := tt.checker.Check(context.Background())
+			if result.Err != nil || result.Code != 0 {
+				t.Fatalf("result = %d %v: %s", result.Code, result.Err, result.Output)
+			}
+			if result.Output != tt.want {
+				t.Errorf("output = %q, want %q", result.Output, tt.want)
+			}
+		})
+	}
+
+	result := (&CommandChecker{Args: []string{"ilias-no-such-program"}, Timeout: 5 * time.Second}).Check(context.Background())
+	if result.Err == nil || result.Code != -1 {
+		t.Errorf("result = %d %v, want an error for a missing program", result.Code, result.Err)
+	}
+}
+
+func TestCommandChecker_Process(t *testing.T) {
+	t.Setenv("ILIAS_TEST_LEAK", "leaked")
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		process config.Process
+		want    string
+	}{
+		{
+			name:    "inherited",
+			process: config.Process{Env: map[string]string{"A": "1"}},
+			want:    "1 leaked",
+		},
+		{
+			name:    "cleared",
+			process: config.Process{Env: map[string]string{"A": "1"}, ClearEnv: true},
+			want:    "1",
+		},
+		{
+			name:    "workdir and stdin",
+			process: config.Process{Workdir: dir, Stdin: "line"},
+			want:    "leaked " + dir + " line",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker := &CommandChecker{
+				Command: `read -r in; echo $A $ILIAS_TEST_LEAK $([ "$PWD" != "` + dir + `" ] || pwd) $in`,
+				Timeout: 5 * time.Second,
+				Process: tt.process,
+			}
+			result := checker.Check(context.Background())
+			if result.Err != nil {
+				t.Fatalf("unexpected error: %v", result.Err)
+			}
+			if result.Output != tt.want {
+				t.Errorf("output = %q, want %q", result.Output, tt.want)
+			}
+		})
+	}
+}
+
 func TestNewChecker(t *testing.T) {
-	_, err := NewChecker("http", "https://example.com", 0)
+	_, err := NewChecker(config.Check{Type: "http", Target: "https://example.com"})
 	if err != nil {
 		t.Fatalf("unexpected error for http: %v", err)
 	}
 
-	_, err = NewChecker("command", "echo test", 0)
+	_, err = NewChecker(config.Check{Type: "command", Target: "echo test"})
 	if err != nil {
 		t.Fatalf("unexpected error for command: %v", err)
 	}
 
-	_, err = NewChecker("ftp", "ftp://example.com", 0)
+	_, err = NewChecker(config.Check{Type: "ftp", Target: "ftp://example.com"})
 	if err == nil {
 		t.Fatal("expected error for unknown type")
 	}
 }
+
+func TestHTTPChecker_MethodHeadersAndBody(t *testing.T) {
+	var gotMethod, gotAuth, gotHost, gotBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMethod = r.Method
+		gotAuth = r.Header.Get("Authorization")
+		gotHost = r.Host
+		b, _ := io.ReadAll(r.Body)
+		gotBody = string(b)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	checker := &HTTPChecker{
+		URL:     server.URL,
+		Method:  http.MethodPost,
+		Headers: map[string]string{"Authorization": "Bearer t0k3n", "Host": "internal.example"},
+		Body:    `{"query": "{ health }"}`,
+		Timeout: 5 * time.Second,
+	}
+	result := checker.Check(context.Background())
+
+	if result.Err != nil {
+		t.Fatalf("unexpected error: %v", result.Err)
+	}
+	if gotMethod != "POST" {
+		t.Errorf("method = %q, want POST", gotMethod)
+	}
+	if gotAuth != "Bearer t0k3n" {
+		t.Errorf("Authorization = %q", gotAuth)
+	}
+	if gotHost != "internal.example" {
+		t.Errorf("Host = %q, want internal.example", gotHost)
+	}
+	if gotBody != `{"query": "{ health }"}` {
+		t.Errorf("body = %q", gotBody)
+	}
+}
+
+func TestRetryChecker(t *testing.T) {
+	dir := t.TempDir()
+	// Fails twice, then succeeds: each attempt appends a line to a file.
+	cmd := &CommandChecker{
+		Command: "echo x >> " + dir + "/n; [ $(wc -l < " + dir + "/n) -ge 3 ] && echo up",
+		Timeout: 5 * time.Second,
+	}
+	failed := func(r Result) bool { return r.Err != nil || r.Code != 0 }
+
+	result := (&RetryChecker{Checker: cmd, Retries: 1, Failed: failed}).Check(context.Background())
+	if result.Code == 0 {
+		t.Fatalf("with 1 retry: code = 0, want the second attempt's failure")
+	}
+
+	result = (&RetryChecker{Checker: cmd, Retries: 5, Failed: failed}).Check(context.Background())
+	if result.Code != 0 || result.Output != "up" {
+		t.Errorf("with 5 retries: result = %+v, want success on the third attempt", result)
+	}
+	data, _ := os.ReadFile(dir + "/n")
+	if n := strings.Count(string(data), "\n"); n != 3 {
+		t.Errorf("attempts = %d, want 3 (no retries after success)", n)
+	}
+}
+
+func TestNewChecker_Retries(t *testing.T) {
+	chk, err := NewChecker(config.Check{Type: "command", Target: "true", Retries: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rc, ok := chk.(*RetryChecker); !ok || rc.Retries != 2 {
+		t.Errorf("checker = %#v, want RetryChecker with 2 retries", chk)
+	}
+}
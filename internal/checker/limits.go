@@ -0,0 +1,48 @@
+package checker
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"github.com/halfdane/ilias/internal/config"
+)
+
+// LimitsArg is the first argument with which ilias runs itself to start a
+// command with resource limits. A program that starts commands with limits
+// must hand such an invocation to ExecLimited before doing anything else.
+const LimitsArg = "__limits"
+
+// ExecLimited sets the limits in args on the current process and replaces
+// it with the command that follows them. It is the other end of limit and
+// never returns: if a limit cannot be set, it exits with 126, and if the
+// command cannot be started, with 127.
+func ExecLimited(args []string) {
+	code, err := execLimited(args)
+	fmt.Fprintf(os.Stderr, "ilias: %v\n", err)
+	os.Exit(code)
+}
+
+// limit makes cmd start through a copy of ilias that sets l on itself
+// before it replaces itself with the command. Unlike ulimit, this works the
+// same whichever shell runs the command, or if none does.
+func limit(cmd *exec.Cmd, l *config.Limits) error {
+	if cmd.Err != nil {
+		return nil // cmd.Run reports it
+	}
+	self, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("setting limits: %w", err)
+	}
+	cpu := (l.CPU.Duration + time.Second - 1) / time.Second
+	cmd.Args = append([]string{self, LimitsArg,
+		strconv.FormatInt(int64(cpu), 10),
+		strconv.FormatInt(int64(l.Memory), 10),
+		strconv.Itoa(l.Processes),
+		cmd.Path,
+	}, cmd.Args...)
+	cmd.Path = self
+	return nil
+}
@@ -0,0 +1,13 @@
+//go:build !linux && !darwin
+
+package checker
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// execLimited fails: resource limits are only supported on Linux and macOS.
+func execLimited(args []string) (int, error) {
+	return 126, fmt.Errorf("resource limits are not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,47 @@
+//go:build linux || darwin
+
+package checker
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"syscall"
+)
+
+// rlimitNPROC is RLIMIT_NPROC, which the syscall package doesn't define.
+var rlimitNPROC = map[string]int{"linux": 6, "darwin": 7}[runtime.GOOS]
+
+// execLimited does the work of ExecLimited. args are the CPU time in
+// seconds, the memory in bytes and the number of processes, each 0 if not
+// limited, then the path of the program and its arguments. It only returns
+// on failure, with the exit code to use.
+func execLimited(args []string) (int, error) {
+	if len(args) < 5 {
+		return 126, errors.New("limits: missing arguments")
+	}
+	resources := []struct {
+		name     string
+		resource int
+	}{
+		{"cpu", syscall.RLIMIT_CPU},
+		{"memory", syscall.RLIMIT_AS},
+		{"processes", rlimitNPROC},
+	}
+	for i, r := range resources {
+		v, err := strconv.ParseUint(args[i], 10, 64)
+		if err != nil {
+			return 126, fmt.Errorf("limits: %s: %w", r.name, err)
+		}
+		if v == 0 {
+			continue
+		}
+		if err := syscall.Setrlimit(r.resource, &syscall.Rlimit{Cur: v, Max: v}); err != nil {
+			return 126, fmt.Errorf("setting the %s limit to %d: %w", r.name, v, err)
+		}
+	}
+	err := syscall.Exec(args[3], args[4:], os.Environ())
+	return 127, fmt.Errorf("%s: %w", args[4], err)
+}
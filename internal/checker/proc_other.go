@@ -0,0 +1,12 @@
+//go:build !unix
+
+package checker
+
+import "os/exec"
+
+// killProcessGroup leaves cmd alone: without process groups, cancelling it
+// only kills bash itself.
+func killProcessGroup(cmd *exec.Cmd) {}
+
+// killGroup does nothing: there is no group to kill.
+func killGroup(cmd *exec.Cmd) error { return nil }
@@ -0,0 +1,87 @@
+package config
+
+import (
+	"cmp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// UnmarshalYAML decodes a generate block, whose command may be a string or
+// a list of program and arguments.
+func (g *Generate) UnmarshalYAML(value *yaml.Node) error {
+	value, args, argsErr := cutArgs(value, "command")
+	type generateAlias Generate
+	var alias generateAlias
+	err := value.Decode(&alias)
+	*g = Generate(alias)
+	g.Args = args
+	return cmp.Or(argsErr, err)
+}
+
+// TargetString returns the target for display: the URL or command string,
+// or the argument list quoted the way a shell would need it.
+func (c Check) TargetString() string {
+	if c.Args != nil {
+		return quoteArgs(c.Args)
+	}
+	return c.Target
+}
+
+// CommandString returns the command for display, like Check.TargetString.
+func (g Generate) CommandString() string {
+	if g.Args != nil {
+		return quoteArgs(g.Args)
+	}
+	return g.Command
+}
+
+// cutArgs looks up key in the mapping n. If its value is a list, it is
+// decoded into args and a copy of n without the key is returned, so that
+// the rest decodes into a struct whose field for key is a string.
+func cutArgs(n *yaml.Node, key string) (*yaml.Node, []string, error) {
+	n = resolve(n)
+	if n == nil || n.Kind != yaml.MappingNode {
+		return n, nil, nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		v := resolve(n.Content[i+1])
+		if n.Content[i].Value != key || v.Kind != yaml.SequenceNode {
+			continue
+		}
+		var args []string
+		err := decodeArgs(v, &args)
+		rest := *n
+		rest.Content = append(append([]*yaml.Node{}, n.Content[:i]...), n.Content[i+2:]...)
+		return &rest, args, err
+	}
+	return n, nil, nil
+}
+
+// decodeArgs decodes a list of program and arguments. Every item must be a
+// plain value: a nested list or map is a mistake, not an argument.
+func decodeArgs(n *yaml.Node, args *[]string) error {
+	*args = make([]string, 0, len(n.Content))
+	for _, item := range n.Content {
+		item = resolve(item)
+		if item.Kind != yaml.ScalarNode {
+			return nodeErrorf(item, "arguments must be strings")
+		}
+		*args = append(*args, item.Value)
+	}
+	return nil
+}
+
+// quoteArgs joins args with spaces, single-quoting those that a shell would
+// otherwise split or interpret.
+func quoteArgs(args []string) string {
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		if a != "" && strings.Trim(a, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_@%+=:,./-") == "" {
+			quoted[i] = a
+		} else {
+			quoted[i] = "'" + strings.ReplaceAll(a, "'", `'\''`) + "'"
+		}
+	}
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,117 @@
+package config
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParse_Args(t *testing.T) {
+	t.Setenv("ILIAS_TEST_HOST", "example.com")
+	cfg, err := ParseStrict([]byte(`title: T
+defaults:
+  shell: sh
+  rules: [{ match: {}, status: { id: ok, label: "✅" } }]
+groups:
+  - name: G
+    tiles:
+      - name: T
+        generate: { command: [touch, /tmp/x y] }
+        slots:
+          - { name: short, check: [ping, -c1, "${ILIAS_TEST_HOST}"] }
+          - { name: map, check: { target: [curl, -s, "it's"], timeout: 5s } }
+          - { name: string, check: "echo $0" }
+          - { name: own, check: { target: "echo $0", shell: zsh } }
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tile := cfg.Groups[0].Tiles[0]
+	if got := tile.Generate.Args; !slices.Equal(got, []string{"touch", "/tmp/x y"}) {
+		t.Errorf("generate args = %q", got)
+	}
+	if got, want := tile.Generate.CommandString(), "touch '/tmp/x y'"; got != want {
+		t.Errorf("CommandString() = %q, want %q", got, want)
+	}
+
+	tests := []struct {
+		slot   int
+		args   []string
+		shell  string
+		target string
+	}{
+		{slot: 0, args: []string{"ping", "-c1", "example.com"}, target: "ping -c1 example.com"},
+		{slot: 1, args: []string{"curl", "-s", "it's"}, target: `curl -s 'it'\''s'`},
+		{slot: 2, shell: "sh", target: "echo $0"},
+		{slot: 3, shell: "zsh", target: "echo $0"},
+	}
+	for _, tt := range tests {
+		c := tile.Slots[tt.slot].Check
+		if c.Type != "command" || !slices.Equal(c.Args, tt.args) || c.Shell != tt.shell {
+			t.Errorf("slot %d: type %q, args %q, shell %q", tt.slot, c.Type, c.Args, c.Shell)
+		}
+		if got := c.TargetString(); got != tt.target {
+			t.Errorf("slot %d: TargetString() = %q, want %q", tt.slot, got, tt.target)
+		}
+	}
+	if c := tile.Slots[1].Check; c.Timeout.Duration == 0 {
+		t.Error("the other keys of a check with a target list should still be decoded")
+	}
+}
+
+func TestParse_ArgsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		tile    string
+		wantErr string
+	}{
+		{
+			name:    "http",
+			tile:    `{ name: T, slots: [{ name: s, check: { type: http, target: [curl, x] } }] }`,
+			wantErr: "check.target must be a URL, not a list, for http checks",
+		},
+		{
+			name:    "shell with list",
+			tile:    `{ name: T, slots: [{ name: s, check: { target: [uptime], shell: sh } }] }`,
+			wantErr: "check.shell does not apply to a target list",
+		},
+		{
+			name:    "shell with http",
+			tile:    `{ name: T, slots: [{ name: s, check: { target: "https://x", shell: sh } }] }`,
+			wantErr: "check.shell is only supported for command checks",
+		},
+		{
+			name:    "empty list",
+			tile:    `{ name: T, slots: [{ name: s, check: [] }] }`,
+			wantErr: "check.target is required",
+		},
+		{
+			name:    "nested list",
+			tile:    `{ name: T, slots: [{ name: s, check: { target: [echo, [a, b]] } }] }`,
+			wantErr: "line 7, column 63: arguments must be strings",
+		},
+		{
+			name:    "generate shell with list",
+			tile:    `{ name: T, generate: { command: [make], shell: sh }, slots: [{ name: s, check: "true" }] }`,
+			wantErr: "generate.shell does not apply to a command list",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(`title: T
+defaults:
+  rules: [{ match: {}, status: { id: ok, label: "✅" } }]
+groups:
+  - name: G
+    tiles:
+      - ` + tt.tile + `
+`))
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %q, want to contain %q", err.Error(), tt.wantErr)
+			}
+		})
+	}
+}
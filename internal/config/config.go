@@ -2,9 +2,17 @@
 package config
 
 import (
+	"cmp"
+	"errors"
 	"fmt"
+	"maps"
+	"math"
 	"os"
+	"path/filepath"
+	"reflect"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -12,33 +20,200 @@ import (
 )
 
 // Defaults defines fallback values applied to slots that omit their own.
+// They can be set globally, per group and per tile; for every slot the
+// innermost value wins. Headers and env are merged key by key; headers only
+// apply to http checks and env only to command checks.
 type Defaults struct {
-	Rules []Rule `yaml:"rules,omitempty"`
+	Rules    []Rule            `yaml:"rules,omitempty"`
+	RuleSet  string            `yaml:"rule_set,omitempty"` // name of a rule set to use as Rules
+	Timeout  Duration          `yaml:"timeout,omitempty"`
+	Retries  int               `yaml:"retries,omitempty"`
+	Interval Duration          `yaml:"interval,omitempty"`
+	Shell    string            `yaml:"shell,omitempty"`
+	Headers  map[string]string `yaml:"headers,omitempty"`
+	Env      map[string]string `yaml:"env,omitempty"`
 }
 
 // Config is the top-level configuration for the dashboard.
 type Config struct {
-	Title    string    `yaml:"title"`
-	Theme    string    `yaml:"theme"`
-	Defaults *Defaults `yaml:"defaults,omitempty"`
-	Refresh  Duration  `yaml:"refresh,omitempty"`
-	Groups   []Group   `yaml:"groups"`
+	Title    string            `yaml:"title"`
+	Theme    string            `yaml:"theme"`
+	Template string            `yaml:"template,omitempty"` // directory with dashboard.tmpl/style.css overrides
+	Themes   map[string]Theme  `yaml:"themes,omitempty"`
+	RuleSets map[string][]Rule `yaml:"rule_sets,omitempty"` // named rules, shared by all files
+	CSS      StringList        `yaml:"css,omitempty"`       // extra stylesheets, inlined after the built-in one
+	Defaults *Defaults         `yaml:"defaults,omitempty"`
+	Severity *Severity         `yaml:"severity,omitempty"`
+	Refresh  Duration          `yaml:"refresh,omitempty"`
+	Notify   *Notify           `yaml:"notify,omitempty"`
+	OnChange string            `yaml:"on_change,omitempty"` // command run when a slot's status changes
+	Groups   []Group           `yaml:"groups"`
+	Include  StringList        `yaml:"include,omitempty"` // files or globs, relative to this file
+
+	// Dir is the directory that relative paths (icons, banners, css,
+	// template) are resolved against. Set by Load; empty for Parse.
+	Dir string `yaml:"-"`
+
+	// secrets holds the values of secret and file references, see Redact.
+	secrets []string
+	// keyPos holds the positions of top-level values, see keyPositions.
+	keyPos map[string]Pos
+	// problems are errors found by decode, reported by validate.
+	problems Errors
+}
+
+// builtinThemes are the themes defined by the embedded stylesheet.
+var builtinThemes = []string{"dark", "light", "auto"}
+
+// Theme maps CSS custom property names (without the leading "--") to values,
+// e.g. {"bg": "#002b36"}. Properties that a theme leaves out keep the dark
+// theme's values.
+type Theme map[string]string
+
+var (
+	cssPropertyName = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+	themeName       = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+)
+
+// StringList is a list of strings that may be written as a single string.
+type StringList []string
+
+// UnmarshalYAML accepts either a scalar or a sequence of scalars.
+func (l *StringList) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*l = StringList{value.Value}
+		return nil
+	}
+	var list []string
+	if err := value.Decode(&list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+// Level is a monitoring-plugin state. Its value is the conventional plugin
+// exit code (0 OK, 1 WARNING, 2 CRITICAL, 3 UNKNOWN).
+type Level int
+
+// Monitoring-plugin states.
+const (
+	LevelOK       Level = 0
+	LevelWarning  Level = 1
+	LevelCritical Level = 2
+	LevelUnknown  Level = 3
+)
+
+// String returns the plugin name of the level, e.g. "CRITICAL".
+func (l Level) String() string {
+	switch l {
+	case LevelOK:
+		return "OK"
+	case LevelWarning:
+		return "WARNING"
+	case LevelCritical:
+		return "CRITICAL"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// Worse reports whether l is more severe than other.
+// The order is OK < WARNING < UNKNOWN < CRITICAL.
+func (l Level) Worse(other Level) bool {
+	rank := func(l Level) int {
+		switch l {
+		case LevelOK:
+			return 0
+		case LevelWarning:
+			return 1
+		case LevelUnknown:
+			return 2
+		default:
+			return 3
+		}
+	}
+	return rank(l) > rank(other)
+}
+
+// Severity maps status ids to monitoring-plugin levels. Status ids that are
+// not listed map to UNKNOWN.
+type Severity struct {
+	OK       []string `yaml:"ok,omitempty"`
+	Warning  []string `yaml:"warning,omitempty"`
+	Critical []string `yaml:"critical,omitempty"`
+	Unknown  []string `yaml:"unknown,omitempty"`
+}
+
+// DefaultSeverity is used when the config has no severity block.
+var DefaultSeverity = Severity{
+	OK:       []string{"ok"},
+	Warning:  []string{"warn", "warning"},
+	Critical: []string{"error", "critical", "down"},
+}
+
+// Level returns the plugin level for a status id. A nil Severity uses
+// DefaultSeverity.
+func (s *Severity) Level(statusID string) Level {
+	if s == nil {
+		s = &DefaultSeverity
+	}
+	for _, m := range []struct {
+		ids   []string
+		level Level
+	}{
+		{s.OK, LevelOK},
+		{s.Warning, LevelWarning},
+		{s.Critical, LevelCritical},
+		{s.Unknown, LevelUnknown},
+	} {
+		for _, id := range m.ids {
+			if id == statusID {
+				return m.level
+			}
+		}
+	}
+	return LevelUnknown
 }
 
 // Group is a named collection of tiles.
 type Group struct {
 	Name  string `yaml:"name"`
 	Tiles []Tile `yaml:"tiles"`
+
+	// Discover, when set, is run at generate time; its output adds further
+	// tiles to the group (see AddDiscovered).
+	Discover *Check `yaml:"discover,omitempty"`
+
+	// Defaults apply to the group's slots, over the global defaults.
+	Defaults *Defaults `yaml:"defaults,omitempty"`
+
+	Maintenance []Maintenance `yaml:"maintenance,omitempty"`
+
+	// Pos is where the group was first declared.
+	Pos Pos `yaml:"-"`
 }
 
 // Tile represents a single dashboard tile.
 type Tile struct {
-	Name     string   `yaml:"name"`
-	Icon     string   `yaml:"icon,omitempty"`
-	Link     string   `yaml:"link,omitempty"`
-	Banner   *Banner  `yaml:"banner,omitempty"`
+	Name     string    `yaml:"name"`
+	Icon     string    `yaml:"icon,omitempty"`
+	Link     string    `yaml:"link,omitempty"`
+	Banner   *Banner   `yaml:"banner,omitempty"`
 	Generate *Generate `yaml:"generate,omitempty"`
-	Slots    []Slot   `yaml:"slots,omitempty"`
+	Slots    []Slot    `yaml:"slots,omitempty"`
+	OnChange string    `yaml:"on_change,omitempty"`
+
+	Maintenance []Maintenance `yaml:"maintenance,omitempty"`
+
+	// Defaults apply to the tile's slots, over the group's defaults.
+	Defaults *Defaults `yaml:"defaults,omitempty"`
+
+	// Discovered is set on tiles added by AddDiscovered.
+	Discovered bool `yaml:"-"`
+
+	// Pos is where the tile was declared.
+	Pos Pos `yaml:"-"`
 }
 
 // Banner defines an optional full-width content block shown below the tile header.
@@ -47,10 +222,26 @@ type Banner struct {
 	Type string `yaml:"type,omitempty"` // "image" (default)
 }
 
-// Generate defines an optional command to run before rendering a tile.
+// Generate defines an optional command to run before rendering a tile. Like
+// a check's target, the command may be a list, which runs without a shell;
+// see Generate.UnmarshalYAML.
 type Generate struct {
 	Command string   `yaml:"command"`
+	Args    []string `yaml:"-"`               // command as a list of program and arguments
+	Shell   string   `yaml:"shell,omitempty"` // runs a string command, default bash
 	Timeout Duration `yaml:"timeout,omitempty"`
+
+	Process `yaml:",inline"`
+}
+
+// Process sets up the process of a command check or generate command.
+// Without it, a command inherits the environment and working directory of
+// ilias and gets no input.
+type Process struct {
+	Env      map[string]string `yaml:"env,omitempty"`       // added to the environment
+	ClearEnv bool              `yaml:"clear_env,omitempty"` // start from an environment with only PATH
+	Workdir  string            `yaml:"workdir,omitempty"`   // relative to the config directory
+	Stdin    string            `yaml:"stdin,omitempty"`
 }
 
 // Slot is a named status indicator on a tile.
@@ -58,41 +249,115 @@ type Slot struct {
 	Name  string `yaml:"name"`
 	Check Check  `yaml:"check"`
 	Rules []Rule `yaml:"rules"`
+
+	// RuleSet names an entry of the top-level rule_sets to use as Rules.
+	RuleSet string `yaml:"rule_set,omitempty"`
+
+	// DependsOn lists "Group/Tile/slot" paths of slots that must be in an
+	// OK status (see Severity) for this slot's check to run at all.
+	DependsOn StringList `yaml:"depends_on,omitempty"`
+
+	// OnChange is a command run when the slot's status changes between
+	// runs. After validation it holds the innermost of the slot's, the
+	// tile's and the global on_change.
+	OnChange string `yaml:"on_change,omitempty"`
+	// OnChangeShell runs OnChange, default bash. It is the innermost shell
+	// of the tile, group and global defaults.
+	OnChangeShell string `yaml:"-"`
+
+	// RulesFrom says where inherited or shared Rules came from: "defaults",
+	// "group defaults", "tile defaults" or `rule set "name"`. It is empty
+	// when the slot defines its own rules.
+	RulesFrom string `yaml:"-"`
+
+	Pos Pos `yaml:"-"`
+}
+
+// SlotPath returns the "Group/Tile/slot" path that identifies a slot on the
+// command line and in reports.
+func SlotPath(group, tile, slot string) string {
+	return group + "/" + tile + "/" + slot
+}
+
+// FindSlot returns the group, tile and slot identified by a "Group/Tile/slot"
+// path. Names may themselves contain slashes, so the path is compared against
+// every slot rather than split. It returns false if no slot matches.
+func (c *Config) FindSlot(path string) (*Group, *Tile, *Slot, bool) {
+	for gi := range c.Groups {
+		g := &c.Groups[gi]
+		for ti := range g.Tiles {
+			t := &g.Tiles[ti]
+			for si := range t.Slots {
+				if SlotPath(g.Name, t.Name, t.Slots[si].Name) == path {
+					return g, t, &t.Slots[si], true
+				}
+			}
+		}
+	}
+	return nil, nil, nil, false
 }
 
 // Check defines how to obtain status information (HTTP request or CLI command).
+//
+// Target, Headers, Body, Env and Stdin may reference ${ENV_VAR},
+// ${secret:ENV_VAR} and ${file:/path}; see interpolate.
 type Check struct {
-	Type    string   `yaml:"type"`   // "http" or "command"
-	Target  string   `yaml:"target"` // URL or command string
-	Timeout Duration `yaml:"timeout,omitempty"`
+	Type     string            `yaml:"type"`            // "http" or "command"
+	Target   string            `yaml:"target"`          // URL or command string
+	Args     []string          `yaml:"-"`               // target given as a list of program and arguments
+	Shell    string            `yaml:"shell,omitempty"` // runs a string target, default bash
+	Timeout  Duration          `yaml:"timeout,omitempty"`
+	Retries  int               `yaml:"retries,omitempty"`  // extra attempts when the check fails to run
+	Interval Duration          `yaml:"interval,omitempty"` // reuse a cached result younger than this
+	Method   string            `yaml:"method,omitempty"`   // http only, default GET
+	Headers  map[string]string `yaml:"headers,omitempty"`  // http only
+	Body     string            `yaml:"body,omitempty"`     // http only
+	Limits   *Limits           `yaml:"limits,omitempty"`   // command only
+	Process  `yaml:",inline"`  // command only
+
+	Pos Pos `yaml:"-"`
 }
 
 // UnmarshalYAML supports both a string shorthand and the full map form.
 // String form:  check: "uptime" or check: "https://example.com"
+// List form:    check: [ping, -c1, example.com]
 // Map form:     check: { target: uptime } or check: { type: command, target: uptime }
 // Type is inferred from the target when omitted: targets starting with
 // http:// or https:// become "http"; everything else becomes "command".
+// A target given as a list is a command run without a shell, see Args.
 func (c *Check) UnmarshalYAML(value *yaml.Node) error {
 	// Try string shorthand first.
 	if value.Kind == yaml.ScalarNode {
 		c.Target = value.Value
 		c.Type = inferCheckType(c.Target)
+		c.Pos = nodePos(value)
 		return nil
 	}
+	if value.Kind == yaml.SequenceNode {
+		c.Type = "command"
+		c.Pos = nodePos(value)
+		return decodeArgs(value, &c.Args)
+	}
+	value, args, argsErr := cutArgs(value, "target")
 
 	// Decode as map using an alias type to avoid infinite recursion.
+	// A *yaml.TypeError (e.g. from an invalid timeout) still leaves the
+	// other fields decoded, so keep them for validation.
 	type checkAlias Check
 	var alias checkAlias
-	if err := value.Decode(&alias); err != nil {
-		return err
-	}
+	err := value.Decode(&alias)
 	*c = Check(alias)
+	c.Pos = nodePos(value)
+	c.Args = args
 
 	// Infer type from target when not specified.
 	if c.Type == "" && c.Target != "" {
 		c.Type = inferCheckType(c.Target)
 	}
-	return nil
+	if c.Type == "" && args != nil {
+		c.Type = "command"
+	}
+	return cmp.Or(argsErr, err)
 }
 
 // inferCheckType returns "http" if the target looks like a URL, "command" otherwise.
@@ -107,6 +372,8 @@ func inferCheckType(target string) string {
 type Rule struct {
 	Match  Match  `yaml:"match"`
 	Status Status `yaml:"status"`
+
+	Pos Pos `yaml:"-"`
 }
 
 // Match defines the conditions for a rule. An empty match is a catch-all.
@@ -123,21 +390,18 @@ func (m *Match) UnmarshalYAML(value *yaml.Node) error {
 		Code   *MatchValue `yaml:"code,omitempty"`
 		Output string      `yaml:"output,omitempty"`
 	}
-	if err := value.Decode(&aux); err != nil {
-		return err
-	}
-
+	err := value.Decode(&aux)
 	m.Code = aux.Code
 
 	if aux.Output != "" {
 		re, err := regexp.Compile(aux.Output)
 		if err != nil {
-			return fmt.Errorf("invalid output regex %q: %w", aux.Output, err)
+			return nodeErrorf(value, "invalid output regex %q: %v", aux.Output, err)
 		}
 		m.Output = re
 	}
 
-	return nil
+	return err
 }
 
 // MatchValue can be an integer (exact match) or a string (regex match).
@@ -161,19 +425,77 @@ func (m *MatchValue) UnmarshalYAML(value *yaml.Node) error {
 	if err := value.Decode(&strVal); err == nil {
 		re, err := regexp.Compile(strVal)
 		if err != nil {
-			return fmt.Errorf("invalid regex in code match %q: %w", strVal, err)
+			return nodeErrorf(value, "invalid regex in code match %q: %v", strVal, err)
 		}
 		m.Regex = re
 		return nil
 	}
 
-	return fmt.Errorf("code match must be an integer or a regex string, got %v", value.Tag)
+	return nodeErrorf(value, "code match must be an integer or a regex string, got %v", value.Tag)
 }
 
 // Status defines a status identifier and its display label.
 type Status struct {
-	ID    string `yaml:"id"`
-	Label string `yaml:"label"`
+	ID    string `yaml:"id" json:"id"`
+	Label string `yaml:"label" json:"label"`
+}
+
+// Limits are resource limits (rlimits) for a command check and everything
+// it starts. Zero means no limit.
+type Limits struct {
+	CPU       Duration `yaml:"cpu,omitempty"`       // CPU time, rounded up to whole seconds
+	Memory    ByteSize `yaml:"memory,omitempty"`    // virtual memory per process
+	Processes int      `yaml:"processes,omitempty"` // processes of the user running ilias
+}
+
+// String describes the limits that are set, e.g. "cpu 10s, memory 512M".
+func (l *Limits) String() string {
+	var parts []string
+	if l.CPU.Duration > 0 {
+		parts = append(parts, "cpu "+l.CPU.String())
+	}
+	if l.Memory > 0 {
+		parts = append(parts, "memory "+l.Memory.String())
+	}
+	if l.Processes > 0 {
+		parts = append(parts, fmt.Sprintf("processes %d", l.Processes))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ByteSize is a number of bytes. In YAML it is a plain number of bytes or a
+// number with a binary unit: "64K", "512M", "2G" (optionally "MiB" or "MB",
+// all meaning powers of 1024).
+type ByteSize int64
+
+var byteUnits = map[string]int64{"": 1, "K": 1 << 10, "M": 1 << 20, "G": 1 << 30, "T": 1 << 40}
+
+// UnmarshalYAML parses a size like 1048576 or "512M".
+func (b *ByteSize) UnmarshalYAML(value *yaml.Node) error {
+	s := strings.TrimSpace(value.Value)
+	i := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+	unit := strings.ToUpper(strings.TrimSpace(s[i:]))
+	unit = strings.TrimSuffix(strings.TrimSuffix(unit, "B"), "I")
+	mult, ok := byteUnits[unit]
+	n, err := strconv.ParseInt(s[:i], 10, 64)
+	if !ok || err != nil || n > math.MaxInt64/mult {
+		return nodeErrorf(value, "invalid size %q (want e.g. 512M)", value.Value)
+	}
+	*b = ByteSize(n * mult)
+	return nil
+}
+
+// String formats the size with the largest unit that divides it.
+func (b ByteSize) String() string {
+	for _, u := range []string{"T", "G", "M", "K"} {
+		if m := byteUnits[u]; b != 0 && int64(b)%m == 0 {
+			return strconv.FormatInt(int64(b)/m, 10) + u
+		}
+	}
+	return strconv.FormatInt(int64(b), 10)
 }
 
 // Duration wraps time.Duration for YAML string parsing (e.g., "10s", "5m").
@@ -189,137 +511,479 @@ func (d *Duration) UnmarshalYAML(value *yaml.Node) error {
 	}
 	dur, err := time.ParseDuration(s)
 	if err != nil {
-		return fmt.Errorf("invalid duration %q: %w", s, err)
+		return nodeErrorf(value, "invalid duration %q: %v", s, err)
 	}
 	d.Duration = dur
 	return nil
 }
 
-// Load reads and parses a config file from the given path.
+// Load reads and parses a config file from the given path, following its
+// include entries. The path may also be a directory, in which case all of its
+// *.yaml and *.yml files are loaded in lexical order (see loadFiles).
+//
+// In addition to the checks done by Parse, files referenced by the config are
+// required to exist, resolved relative to the config file (or directory).
 func Load(path string) (*Config, error) {
-	data, err := os.ReadFile(path)
+	return load(path, false)
+}
+
+func load(path string, strict bool) (*Config, error) {
+	cfg, dir, err := loadFiles(path, strict)
+	if err != nil {
+		return nil, err
+	}
+	errs := cfg.validate()
+	cfg.Dir = dir
+	cfg.resolveWorkdirs()
+	errs = append(errs, cfg.checkFiles(dir)...)
+	errs = append(errs, cfg.checkWorkdirs()...)
+	if len(errs) > 0 {
+		errs.sort()
+		return nil, errs
+	}
+	return cfg, nil
+}
+
+// resolveWorkdirs makes the relative working directories of commands
+// absolute, against Dir. Without a Dir, as after Parse, they stay relative
+// to the working directory of ilias.
+func (c *Config) resolveWorkdirs() {
+	for gi := range c.Groups {
+		g := &c.Groups[gi]
+		if g.Discover != nil {
+			g.Discover.Workdir = c.resolveWorkdir(g.Discover.Workdir)
+		}
+		for ti := range g.Tiles {
+			c.resolveTileWorkdirs(&g.Tiles[ti])
+		}
+	}
+}
+
+func (c *Config) resolveTileWorkdirs(t *Tile) {
+	if t.Generate != nil {
+		t.Generate.Workdir = c.resolveWorkdir(t.Generate.Workdir)
+	}
+	for si := range t.Slots {
+		t.Slots[si].Check.Workdir = c.resolveWorkdir(t.Slots[si].Check.Workdir)
+	}
+}
+
+func (c *Config) resolveWorkdir(dir string) string {
+	if dir == "" || c.Dir == "" || filepath.IsAbs(dir) {
+		return dir
+	}
+	return filepath.Join(c.Dir, dir)
+}
+
+// checkFiles verifies that referenced stylesheets exist.
+func (c *Config) checkFiles(dir string) Errors {
+	var errs Errors
+	for i, css := range c.CSS {
+		path := css
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			errs.add(c.at("css"), "css[%d]: %v", i, err)
+		} else if info.IsDir() {
+			errs.add(c.at("css"), "css[%d]: %s is a directory", i, path)
+		}
+	}
+	return errs
+}
+
+// checkWorkdirs verifies that the working directories of commands exist.
+// Relative ones must have been resolved by resolveWorkdirs.
+func (c *Config) checkWorkdirs() Errors {
+	var errs Errors
+	for gi, g := range c.Groups {
+		if g.Discover != nil {
+			pos := g.Discover.Pos
+			if pos.IsZero() {
+				pos = g.Pos
+			}
+			checkWorkdir(&errs, pos, fmt.Sprintf("group[%d] %q", gi, g.Name), "discover", g.Discover.Workdir)
+		}
+		for ti, t := range g.Tiles {
+			checkTileWorkdirs(&errs, gi, g.Name, ti, t)
+		}
+	}
+	return errs
+}
+
+func checkTileWorkdirs(errs *Errors, gi int, gname string, ti int, t Tile) {
+	prefix := fmt.Sprintf("group[%d] %q, tile[%d] %q", gi, gname, ti, t.Name)
+	if t.Generate != nil {
+		checkWorkdir(errs, t.Pos, prefix, "generate", t.Generate.Workdir)
+	}
+	for si, s := range t.Slots {
+		pos := s.Check.Pos
+		if pos.IsZero() {
+			pos = s.Pos
+		}
+		checkWorkdir(errs, pos, fmt.Sprintf("%s, slot[%d] %q", prefix, si, s.Name), "check", s.Check.Workdir)
+	}
+}
+
+func checkWorkdir(errs *Errors, pos Pos, prefix, key, dir string) {
+	if dir == "" {
+		return
+	}
+	info, err := os.Stat(dir)
 	if err != nil {
-		return nil, fmt.Errorf("reading config file: %w", err)
+		errs.add(pos, "%s: %s.workdir: %v", prefix, key, err)
+	} else if !info.IsDir() {
+		errs.add(pos, "%s: %s.workdir: %s is not a directory", prefix, key, dir)
 	}
-	return Parse(data)
 }
 
-// Parse parses YAML data into a Config.
+// Parse parses YAML data into a Config. Includes need a file to resolve
+// against, so they are only supported by Load.
+//
+// All problems found are returned together as Errors.
 func Parse(data []byte) (*Config, error) {
+	return parse(data, false)
+}
+
+func parse(data []byte, strict bool) (*Config, error) {
+	cfg, err := decode(data, "", strict)
+	if err != nil {
+		return nil, err
+	}
+	if len(cfg.Include) > 0 {
+		cfg.problems.add(cfg.at("include"), "include is only supported when loading from a file")
+	}
+	if errs := cfg.validate(); len(errs) > 0 {
+		return nil, errs
+	}
+	return cfg, nil
+}
+
+// decode unmarshals YAML data without validating it. file is recorded in
+// every position; it may be empty for configs parsed from memory.
+//
+// Templates are expanded first (see expandTemplates). Syntax errors are
+// returned as err. Values of the wrong type, invalid regexes
+// and durations, and in strict mode unknown keys, do not stop decoding; they
+// are kept in cfg.problems and reported by validate together with everything
+// else.
+func decode(data []byte, file string, strict bool) (*Config, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, decodeError(err, file)
+	}
 	var cfg Config
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
-		return nil, fmt.Errorf("parsing config: %w", err)
+	cfg.problems = expandTemplates(&doc, file)
+	if len(doc.Content) > 0 {
+		if err := doc.Decode(&cfg); err != nil {
+			var te *yaml.TypeError
+			if !errors.As(err, &te) {
+				return nil, decodeError(err, file)
+			}
+			cfg.problems = append(cfg.problems, typeErrors(te, file)...)
+		}
 	}
-	if err := cfg.validate(); err != nil {
-		return nil, err
+	if strict && len(doc.Content) > 0 {
+		checkKeys(doc.Content[0], reflect.TypeOf(cfg), "", file, &cfg.problems)
 	}
+	cfg.keyPos = keyPositions(&doc)
+	cfg.setPositions(&doc, file)
 	return &cfg, nil
 }
 
-// validate checks the config for required fields and consistency.
-func (c *Config) validate() error {
+// validate checks the config for required fields and consistency. It reports
+// every problem it finds, including those left by decode, rather than
+// stopping at the first.
+func (c *Config) validate() Errors {
+	errs := c.problems
+
 	if c.Title == "" {
-		return fmt.Errorf("config: title is required")
+		errs.add(c.at("title"), "title is required")
 	}
 
 	if c.Theme == "" {
 		c.Theme = "dark"
 	}
-	if c.Theme != "dark" && c.Theme != "light" {
-		return fmt.Errorf("config: theme must be \"dark\" or \"light\", got %q", c.Theme)
+	for _, name := range slices.Sorted(maps.Keys(c.Themes)) {
+		pos := c.at("themes." + name)
+		if !themeName.MatchString(name) {
+			errs.add(pos, "themes: invalid theme name %q (letters, digits, '-' and '_' only)", name)
+		}
+		theme := c.Themes[name]
+		for _, prop := range slices.Sorted(maps.Keys(theme)) {
+			if !cssPropertyName.MatchString(strings.TrimPrefix(prop, "--")) {
+				errs.add(pos, "themes %q: invalid CSS property name %q", name, prop)
+			}
+			if strings.ContainsAny(theme[prop], "{};<>") {
+				errs.add(pos, "themes %q: value of %q must not contain any of {};<>", name, prop)
+			}
+		}
+	}
+	if _, ok := c.Themes[c.Theme]; !ok && !slices.Contains(builtinThemes, c.Theme) {
+		errs.add(c.at("theme"), "theme must be \"dark\", \"light\", \"auto\" or defined under themes, got %q", c.Theme)
 	}
 
-	// Validate default rules if present.
-	if c.Defaults != nil {
-		for ri, r := range c.Defaults.Rules {
-			if r.Status.ID == "" {
-				return fmt.Errorf("config: defaults, rule[%d]: status.id is required", ri)
-			}
-			if r.Status.Label == "" {
-				return fmt.Errorf("config: defaults, rule[%d]: status.label is required", ri)
+	c.validateRuleSets(&errs)
+	c.useRuleSets(&errs)
+	validateDefaults(&errs, c.Defaults, c.at("defaults"), "defaults")
+
+	if c.Severity != nil {
+		seen := make(map[string]string)
+		for _, m := range []struct {
+			ids  []string
+			name string
+		}{
+			{c.Severity.OK, "ok"},
+			{c.Severity.Warning, "warning"},
+			{c.Severity.Critical, "critical"},
+			{c.Severity.Unknown, "unknown"},
+		} {
+			for _, id := range m.ids {
+				if prev, ok := seen[id]; ok {
+					errs.add(c.at("severity"), "severity: status id %q is listed under both %s and %s", id, prev, m.name)
+					continue
+				}
+				seen[id] = m.name
 			}
 		}
 	}
 
 	if len(c.Groups) == 0 {
-		return fmt.Errorf("config: at least one group is required")
+		errs.add(c.at("groups"), "at least one group is required")
 	}
 
 	for gi, g := range c.Groups {
 		if g.Name == "" {
-			return fmt.Errorf("config: group[%d]: name is required", gi)
+			errs.add(g.Pos, "group[%d]: name is required", gi)
+		} else if len(g.Tiles) == 0 && g.Discover == nil {
+			errs.add(g.Pos, "group[%d] %q: at least one tile is required", gi, g.Name)
 		}
-		if len(g.Tiles) == 0 {
-			return fmt.Errorf("config: group[%d] %q: at least one tile is required", gi, g.Name)
+		if g.Discover != nil {
+			pos := g.Discover.Pos
+			if pos.IsZero() {
+				pos = g.Pos
+			}
+			validateCheck(&errs, pos, fmt.Sprintf("group[%d] %q", gi, g.Name), "discover", *g.Discover)
+			if g.Discover.Interval.Duration != 0 {
+				errs.add(pos, "group[%d] %q: discover.interval is not supported; discovery runs every time", gi, g.Name)
+			}
 		}
+		validateDefaults(&errs, g.Defaults, g.Pos, fmt.Sprintf("group[%d] %q, defaults", gi, g.Name))
+		validateMaintenance(&errs, g.Maintenance, fmt.Sprintf("group[%d] %q", gi, g.Name))
 		for ti := range g.Tiles {
-			// Apply default rules to slots that don't define their own.
-			if c.Defaults != nil && len(c.Defaults.Rules) > 0 {
-				for si := range g.Tiles[ti].Slots {
-					if len(g.Tiles[ti].Slots[si].Rules) == 0 {
-						g.Tiles[ti].Slots[si].Rules = c.Defaults.Rules
+			c.applyDefaults(&c.Groups[gi], &g.Tiles[ti])
+			validateTile(&errs, gi, g.Name, ti, g.Tiles[ti])
+		}
+	}
+
+	all := func(int, *Tile) bool { return true }
+	c.validateDependencies(&errs, all, all)
+	c.interpolate(&errs)
+	c.validateNotify(&errs)
+	errs.sort()
+	return errs
+}
+
+// applyDefaults fills in what the slots of tile t in group g leave out from
+// the tile, group and global defaults, innermost first. It also resolves
+// on_change and its shell.
+func (c *Config) applyDefaults(g *Group, t *Tile) {
+	layers := []struct {
+		d    *Defaults
+		from string
+	}{
+		{t.Defaults, "tile defaults"},
+		{g.Defaults, "group defaults"},
+		{c.Defaults, "defaults"},
+	}
+	for si := range t.Slots {
+		s := &t.Slots[si]
+		s.OnChange = cmp.Or(s.OnChange, t.OnChange, c.OnChange)
+		for _, l := range layers {
+			if l.d == nil {
+				continue
+			}
+			if len(s.Rules) == 0 && len(l.d.Rules) > 0 {
+				s.Rules = l.d.Rules
+				s.RulesFrom = l.from
+			}
+			if s.Check.Timeout.Duration == 0 {
+				s.Check.Timeout = l.d.Timeout
+			}
+			if s.Check.Retries == 0 && l.d.Retries > 0 {
+				s.Check.Retries = l.d.Retries
+			}
+			if s.Check.Interval.Duration == 0 && l.d.Interval.Duration > 0 {
+				s.Check.Interval = l.d.Interval
+			}
+			if s.Check.Shell == "" && s.Check.Type == "command" && s.Check.Args == nil {
+				s.Check.Shell = l.d.Shell
+			}
+			if s.OnChangeShell == "" && s.OnChange != "" {
+				s.OnChangeShell = l.d.Shell
+			}
+			if s.Check.Type == "command" {
+				for k, v := range l.d.Env {
+					if _, ok := s.Check.Env[k]; ok {
+						continue
+					}
+					if s.Check.Env == nil {
+						s.Check.Env = make(map[string]string)
 					}
+					s.Check.Env[k] = v
 				}
 			}
-			if err := validateTile(gi, g.Name, ti, g.Tiles[ti]); err != nil {
-				return err
+			if s.Check.Type == "http" {
+				for k, v := range l.d.Headers {
+					if _, ok := s.Check.Headers[k]; ok {
+						continue
+					}
+					if s.Check.Headers == nil {
+						s.Check.Headers = make(map[string]string)
+					}
+					s.Check.Headers[k] = v
+				}
 			}
 		}
 	}
-	return nil
 }
 
-func validateTile(gi int, gname string, ti int, t Tile) error {
-	prefix := fmt.Sprintf("config: group[%d] %q, tile[%d]", gi, gname, ti)
+func validateTile(errs *Errors, gi int, gname string, ti int, t Tile) {
+	prefix := fmt.Sprintf("group[%d] %q, tile[%d]", gi, gname, ti)
 
 	if t.Name == "" {
-		return fmt.Errorf("%s: name is required", prefix)
+		errs.add(t.Pos, "%s: name is required", prefix)
+	} else {
+		prefix = fmt.Sprintf("group[%d] %q, tile[%d] %q", gi, gname, ti, t.Name)
 	}
-	prefix = fmt.Sprintf("config: group[%d] %q, tile[%d] %q", gi, gname, ti, t.Name)
+	validateDefaults(errs, t.Defaults, t.Pos, prefix+", defaults")
+	validateMaintenance(errs, t.Maintenance, prefix)
 
-	if t.Generate != nil && t.Generate.Command == "" {
-		return fmt.Errorf("%s: generate.command is required when generate is specified", prefix)
+	if g := t.Generate; g != nil {
+		if g.Command == "" && (len(g.Args) == 0 || g.Args[0] == "") {
+			errs.add(t.Pos, "%s: generate.command is required when generate is specified", prefix)
+		}
+		if g.Shell != "" && g.Args != nil {
+			errs.add(t.Pos, "%s: generate.shell does not apply to a command list, which runs without a shell", prefix)
+		}
+		validateEnv(errs, t.Pos, prefix, "generate", g.Env)
 	}
 
 	for si, s := range t.Slots {
-		if err := validateSlot(prefix, si, s); err != nil {
-			return err
-		}
+		validateSlot(errs, prefix, si, s)
 	}
-	return nil
 }
 
-func validateSlot(prefix string, si int, s Slot) error {
+func validateSlot(errs *Errors, prefix string, si int, s Slot) {
 	slotPrefix := fmt.Sprintf("%s, slot[%d]", prefix, si)
 
 	if s.Name == "" {
-		return fmt.Errorf("%s: name is required", slotPrefix)
+		errs.add(s.Pos, "%s: name is required", slotPrefix)
+	} else {
+		slotPrefix = fmt.Sprintf("%s, slot[%d] %q", prefix, si, s.Name)
 	}
-	slotPrefix = fmt.Sprintf("%s, slot[%d] %q", prefix, si, s.Name)
 
-	if s.Check.Type == "" {
-		return fmt.Errorf("%s: check.type is required (could not be inferred)", slotPrefix)
+	// A slot without a check block has no check position.
+	checkPos := s.Check.Pos
+	if checkPos.IsZero() {
+		checkPos = s.Pos
 	}
-	if s.Check.Type != "http" && s.Check.Type != "command" {
-		return fmt.Errorf("%s: check.type must be \"http\" or \"command\", got %q", slotPrefix, s.Check.Type)
+	validateCheck(errs, checkPos, slotPrefix, "check", s.Check)
+
+	if len(s.Rules) == 0 && s.RuleSet == "" {
+		errs.add(s.Pos, "%s: at least one rule is required", slotPrefix)
 	}
-	if s.Check.Target == "" {
-		return fmt.Errorf("%s: check.target is required", slotPrefix)
+
+	// Inherited rules were already checked as part of defaults.
+	if s.RulesFrom == "" {
+		for ri, r := range s.Rules {
+			// Output regexes are already compiled during YAML unmarshalling.
+			validateStatus(errs, r, fmt.Sprintf("%s, rule[%d]", slotPrefix, ri))
+		}
 	}
+}
 
-	if len(s.Rules) == 0 {
-		return fmt.Errorf("%s: at least one rule is required", slotPrefix)
+// validateCheck checks the check (or discover) block key of a slot or group.
+func validateCheck(errs *Errors, pos Pos, prefix, key string, c Check) {
+	switch c.Type {
+	case "http", "command":
+	case "":
+		errs.add(pos, "%s: %s.type is required (could not be inferred)", prefix, key)
+	default:
+		errs.add(pos, "%s: %s.type must be \"http\" or \"command\", got %q", prefix, key, c.Type)
+	}
+	if c.Target == "" && (len(c.Args) == 0 || c.Args[0] == "") {
+		errs.add(pos, "%s: %s.target is required", prefix, key)
+	}
+	if c.Args != nil && c.Type == "http" {
+		errs.add(pos, "%s: %s.target must be a URL, not a list, for http checks", prefix, key)
+	}
+	if c.Shell != "" {
+		if c.Type != "command" {
+			errs.add(pos, "%s: %s.shell is only supported for command checks", prefix, key)
+		} else if c.Args != nil {
+			errs.add(pos, "%s: %s.shell does not apply to a target list, which runs without a shell", prefix, key)
+		}
 	}
+	if c.Type != "http" && (c.Method != "" || len(c.Headers) > 0 || c.Body != "") {
+		errs.add(pos, "%s: %s.method, %s.headers and %s.body are only supported for http checks", prefix, key, key, key)
+	}
+	if c.Retries < 0 {
+		errs.add(pos, "%s: %s.retries must not be negative", prefix, key)
+	}
+	if c.Interval.Duration < 0 {
+		errs.add(pos, "%s: %s.interval must not be negative", prefix, key)
+	}
+	if c.Type != "command" && (len(c.Env) > 0 || c.ClearEnv || c.Workdir != "" || c.Stdin != "") {
+		errs.add(pos, "%s: %s.env, %s.clear_env, %s.workdir and %s.stdin are only supported for command checks", prefix, key, key, key, key)
+	}
+	validateEnv(errs, pos, prefix, key, c.Env)
+	if c.Limits != nil {
+		if c.Type != "command" {
+			errs.add(pos, "%s: %s.limits are only supported for command checks", prefix, key)
+		}
+		if c.Limits.CPU.Duration < 0 || c.Limits.Processes < 0 {
+			errs.add(pos, "%s: %s.limits must not be negative", prefix, key)
+		}
+	}
+}
 
-	for ri, r := range s.Rules {
-		if r.Status.ID == "" {
-			return fmt.Errorf("%s, rule[%d]: status.id is required", slotPrefix, ri)
+// validateEnv checks the variable names of the env of key.
+func validateEnv(errs *Errors, pos Pos, prefix, key string, env map[string]string) {
+	for _, name := range slices.Sorted(maps.Keys(env)) {
+		if name == "" || strings.ContainsAny(name, "=\x00") {
+			errs.add(pos, "%s: %s.env: invalid variable name %q", prefix, key, name)
 		}
-		if r.Status.Label == "" {
-			return fmt.Errorf("%s, rule[%d]: status.label is required", slotPrefix, ri)
+	}
+}
+
+// validateDefaults checks a defaults block. Its rules are checked here,
+// once, rather than in every slot that inherits them.
+func validateDefaults(errs *Errors, d *Defaults, pos Pos, prefix string) {
+	if d == nil {
+		return
+	}
+	// The rules of a rule set are checked as part of rule_sets.
+	if d.RuleSet == "" {
+		for ri, r := range d.Rules {
+			validateStatus(errs, r, fmt.Sprintf("%s, rule[%d]", prefix, ri))
 		}
-		// Output regex is already compiled during YAML unmarshalling.
 	}
+	if d.Retries < 0 {
+		errs.add(pos, "%s: retries must not be negative", prefix)
+	}
+	if d.Interval.Duration < 0 {
+		errs.add(pos, "%s: interval must not be negative", prefix)
+	}
+}
 
-	return nil
+// validateStatus checks that a rule has a complete status.
+func validateStatus(errs *Errors, r Rule, prefix string) {
+	if r.Status.ID == "" {
+		errs.add(r.Pos, "%s: status.id is required", prefix)
+	}
+	if r.Status.Label == "" {
+		errs.add(r.Pos, "%s: status.label is required", prefix)
+	}
 }
@@ -1,10 +1,14 @@
 package config
 
 import (
+	"maps"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
 func TestParse_ValidConfig(t *testing.T) {
@@ -384,6 +388,114 @@ groups:
 	}
 }
 
+func TestParse_GroupAndTileDefaults(t *testing.T) {
+	yaml := `
+title: "Test"
+defaults:
+  timeout: 5s
+  rules:
+    - match: {}
+      status: { id: global, label: "G" }
+groups:
+  - name: "Web"
+    defaults:
+      retries: 2
+      headers: { Accept: application/json, X-Env: prod }
+      rules:
+        - match: {}
+          status: { id: group, label: "W" }
+    tiles:
+      - name: "API"
+        defaults:
+          timeout: 2s
+          headers: { X-Env: staging }
+          rules:
+            - match: {}
+              status: { id: tile, label: "A" }
+        slots:
+          - { name: "health", check: "https://api/health" }
+          - name: "own"
+            check: { target: "https://api/own", retries: 5, headers: { Accept: text/plain } }
+            rules:
+              - match: {}
+                status: { id: own, label: "O" }
+      - name: "Site"
+        slots:
+          - { name: "up", check: "https://site/" }
+  - name: "Shell"
+    tiles:
+      - name: "Host"
+        slots:
+          - { name: "uptime", check: "uptime" }
+`
+	cfg, err := Parse([]byte(yaml))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		slot      Slot
+		rulesFrom string
+		status    string
+		timeout   time.Duration
+		retries   int
+		headers   map[string]string
+	}{
+		{cfg.Groups[0].Tiles[0].Slots[0], "tile defaults", "tile", 2 * time.Second, 2,
+			map[string]string{"Accept": "application/json", "X-Env": "staging"}},
+		{cfg.Groups[0].Tiles[0].Slots[1], "", "own", 2 * time.Second, 5,
+			map[string]string{"Accept": "text/plain", "X-Env": "staging"}},
+		{cfg.Groups[0].Tiles[1].Slots[0], "group defaults", "group", 5 * time.Second, 2,
+			map[string]string{"Accept": "application/json", "X-Env": "prod"}},
+		{cfg.Groups[1].Tiles[0].Slots[0], "defaults", "global", 5 * time.Second, 0, nil},
+	}
+	for _, tt := range tests {
+		s := tt.slot
+		if s.RulesFrom != tt.rulesFrom || s.Rules[0].Status.ID != tt.status {
+			t.Errorf("%s: rules %q from %q, want %q from %q", s.Name, s.Rules[0].Status.ID, s.RulesFrom, tt.status, tt.rulesFrom)
+		}
+		if s.Check.Timeout.Duration != tt.timeout || s.Check.Retries != tt.retries {
+			t.Errorf("%s: timeout %s, retries %d, want %s and %d", s.Name, s.Check.Timeout.Duration, s.Check.Retries, tt.timeout, tt.retries)
+		}
+		if !maps.Equal(s.Check.Headers, tt.headers) {
+			t.Errorf("%s: headers = %v, want %v", s.Name, s.Check.Headers, tt.headers)
+		}
+	}
+}
+
+func TestParse_GroupDefaults_Validated(t *testing.T) {
+	yaml := `
+title: "Test"
+groups:
+  - name: "G"
+    defaults:
+      retries: -1
+      rules:
+        - match: {}
+          status: { label: "✅" }
+    tiles:
+      - name: "T"
+        slots:
+          - name: "s"
+            check: { type: command, target: "echo ok" }
+`
+	_, err := Parse([]byte(yaml))
+	if err == nil {
+		t.Fatal("expected errors for invalid group defaults")
+	}
+	for _, want := range []string{
+		`line 8, column 11: group[0] "G", defaults, rule[0]: status.id is required`,
+		`line 4, column 5: group[0] "G", defaults: retries must not be negative`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error = %q, want to contain %q", err.Error(), want)
+		}
+	}
+	if strings.Contains(err.Error(), "check.retries") {
+		t.Errorf("error = %q, want the negative retries reported once, in defaults", err.Error())
+	}
+}
+
 func TestParse_CheckStringShorthand_Command(t *testing.T) {
 	yaml := `
 title: "Test"
@@ -517,3 +629,382 @@ groups:
 		t.Errorf("check.timeout = %v, want 5s", slot.Check.Timeout)
 	}
 }
+
+func TestParse_Severity(t *testing.T) {
+	yaml := `
+title: "Test"
+severity:
+  ok: [green]
+  critical: [red, "error"]
+defaults:
+  rules:
+    - match: {}
+      status: { id: green, label: "✅" }
+groups:
+  - name: "G"
+    tiles:
+      - name: "T"
+        slots:
+          - name: "s"
+            check: "true"
+`
+	cfg, err := Parse([]byte(yaml))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := cfg.Severity.Level("green"); got != LevelOK {
+		t.Errorf("level(green) = %v, want OK", got)
+	}
+	if got := cfg.Severity.Level("red"); got != LevelCritical {
+		t.Errorf("level(red) = %v, want CRITICAL", got)
+	}
+	if got := cfg.Severity.Level("warn"); got != LevelUnknown {
+		t.Errorf("level(warn) = %v, want UNKNOWN", got)
+	}
+}
+
+func TestParse_Severity_DuplicateID(t *testing.T) {
+	yaml := `
+title: "Test"
+severity:
+  ok: [ok]
+  warning: [ok]
+groups:
+  - name: "G"
+    tiles:
+      - name: "T"
+`
+	_, err := Parse([]byte(yaml))
+	if err == nil {
+		t.Fatal("expected error for status id listed twice")
+	}
+	if !strings.Contains(err.Error(), "listed under both ok and warning") {
+		t.Errorf("error = %q, want to mention both levels", err.Error())
+	}
+}
+
+func TestSeverity_NilUsesDefaults(t *testing.T) {
+	var s *Severity
+	tests := map[string]Level{
+		"ok":      LevelOK,
+		"warn":    LevelWarning,
+		"error":   LevelCritical,
+		"down":    LevelCritical,
+		"unknown": LevelUnknown,
+	}
+	for id, want := range tests {
+		if got := s.Level(id); got != want {
+			t.Errorf("level(%q) = %v, want %v", id, got, want)
+		}
+	}
+}
+
+func TestLevel_Worse(t *testing.T) {
+	order := []Level{LevelOK, LevelWarning, LevelUnknown, LevelCritical}
+	for i := 1; i < len(order); i++ {
+		if !order[i].Worse(order[i-1]) {
+			t.Errorf("%v should be worse than %v", order[i], order[i-1])
+		}
+		if order[i-1].Worse(order[i]) {
+			t.Errorf("%v should not be worse than %v", order[i-1], order[i])
+		}
+	}
+}
+
+func TestFindSlot_NamesWithSlashes(t *testing.T) {
+	yaml := `
+title: "Test"
+defaults:
+  rules:
+    - match: {}
+      status: { id: ok, label: "✅" }
+groups:
+  - name: "System"
+    tiles:
+      - name: "Disk (/home)"
+        slots:
+          - name: "usage"
+            check: "df /home"
+      - name: "Disk"
+        slots:
+          - name: "usage"
+            check: "df /"
+`
+	cfg, err := Parse([]byte(yaml))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	g, tile, slot, ok := cfg.FindSlot("System/Disk (/home)/usage")
+	if !ok {
+		t.Fatal("slot not found")
+	}
+	if g.Name != "System" || tile.Name != "Disk (/home)" || slot.Check.Target != "df /home" {
+		t.Errorf("found %q/%q/%q (%s), want System/Disk (/home)/usage", g.Name, tile.Name, slot.Name, slot.Check.Target)
+	}
+	if slot.RulesFrom != "defaults" {
+		t.Errorf("rulesFrom = %q, want %q", slot.RulesFrom, "defaults")
+	}
+
+	if _, _, _, ok := cfg.FindSlot("System/Disk/missing"); ok {
+		t.Error("expected missing slot not to be found")
+	}
+}
+
+func TestParse_Themes(t *testing.T) {
+	base := `
+title: "Test"
+defaults:
+  rules:
+    - match: {}
+      status: { id: ok, label: "✅" }
+groups:
+  - name: "G"
+    tiles:
+      - name: "T"
+        slots:
+          - name: "s"
+            check: "true"
+`
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{name: "auto", yaml: "theme: auto\n"},
+		{name: "custom theme", yaml: "theme: solarized\nthemes:\n  solarized: { bg: \"#002b36\", --text: \"#839496\" }\n"},
+		{name: "undefined theme", yaml: "theme: solarized\n", wantErr: "defined under themes"},
+		{name: "bad property", yaml: "themes:\n  x: { \"b g\": red }\n", wantErr: "invalid CSS property name"},
+		{name: "injection", yaml: "themes:\n  x: { bg: \"red; } body { display: none\" }\n", wantErr: "must not contain"},
+		{name: "bad theme name", yaml: "themes:\n  \"a b\": { bg: red }\n", wantErr: "invalid theme name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.yaml + base))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoad_CSSFiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "extra.css"), []byte(".x{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	write := func(css string) string {
+		path := filepath.Join(dir, "config.yaml")
+		yaml := "title: T\ncss: " + css + `
+defaults:
+  rules:
+    - match: {}
+      status: { id: ok, label: "✅" }
+groups:
+  - name: "G"
+    tiles:
+      - name: "T"
+        slots:
+          - name: "s"
+            check: "true"
+`
+		if err := os.WriteFile(path, []byte(yaml), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	cfg, err := Load(write("extra.css"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cfg.CSS) != 1 || cfg.CSS[0] != "extra.css" {
+		t.Errorf("css = %v, want [extra.css]", cfg.CSS)
+	}
+
+	_, err = Load(write("[extra.css, missing.css]"))
+	if err == nil || !strings.Contains(err.Error(), "css[1]") {
+		t.Errorf("err = %v, want css[1] error for missing file", err)
+	}
+}
+
+func TestParse_Limits(t *testing.T) {
+	cfg, err := ParseStrict([]byte(`title: T
+defaults:
+  rules: [{ match: {}, status: { id: ok, label: "✅" } }]
+groups:
+  - name: G
+    tiles:
+      - name: T
+        slots:
+          - name: s
+            check: { target: "true", limits: { cpu: 10s, memory: 512MiB, processes: 64 } }
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	l := cfg.Groups[0].Tiles[0].Slots[0].Check.Limits
+	if l == nil || l.CPU.Duration != 10*time.Second || l.Memory != 512<<20 || l.Processes != 64 {
+		t.Fatalf("limits = %+v", l)
+	}
+	if got, want := l.String(), "cpu 10s, memory 512M, processes 64"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+
+	_, err = Parse([]byte(`title: T
+defaults:
+  rules: [{ match: {}, status: { id: ok, label: "✅" } }]
+groups:
+  - name: G
+    tiles:
+      - name: T
+        slots:
+          - { name: web, check: { type: http, target: "http://x", limits: { cpu: 1s } } }
+          - { name: neg, check: { target: "true", limits: { processes: -1 } } }
+`))
+	for _, want := range []string{"check.limits are only supported for command checks", "check.limits must not be negative"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("error = %v, want to contain %q", err, want)
+		}
+	}
+}
+
+func TestParse_Process(t *testing.T) {
+	t.Setenv("ILIAS_TEST_TOKEN", "s3cret")
+	cfg, err := ParseStrict([]byte(`title: T
+defaults:
+  env: { LANG: C, TZ: UTC }
+  rules: [{ match: {}, status: { id: ok, label: "✅" } }]
+groups:
+  - name: G
+    tiles:
+      - name: T
+        generate: { command: make, env: { OUT: x }, clear_env: true, workdir: build, stdin: data }
+        slots:
+          - name: cmd
+            check:
+              target: ./check.sh
+              env: { TZ: Europe/Berlin, TOKEN: "${ILIAS_TEST_TOKEN}" }
+              clear_env: true
+              workdir: scripts
+              stdin: "token=${ILIAS_TEST_TOKEN}"
+          - { name: web, check: "https://example.com" }
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tile := cfg.Groups[0].Tiles[0]
+	want := Process{Env: map[string]string{"OUT": "x"}, ClearEnv: true, Workdir: "build", Stdin: "data"}
+	if got := tile.Generate.Process; !maps.Equal(got.Env, want.Env) || got.ClearEnv != want.ClearEnv || got.Workdir != want.Workdir || got.Stdin != want.Stdin {
+		t.Errorf("generate = %+v, want %+v", got, want)
+	}
+
+	chk := tile.Slots[0].Check
+	wantEnv := map[string]string{"LANG": "C", "TZ": "Europe/Berlin", "TOKEN": "s3cret"}
+	if !maps.Equal(chk.Env, wantEnv) {
+		t.Errorf("env = %v, want %v (own values over defaults, interpolated)", chk.Env, wantEnv)
+	}
+	if !chk.ClearEnv || chk.Workdir != "scripts" || chk.Stdin != "token=s3cret" {
+		t.Errorf("check = %+v", chk.Process)
+	}
+	if env := tile.Slots[1].Check.Env; env != nil {
+		t.Errorf("http check got env %v from the defaults", env)
+	}
+
+	_, err = Parse([]byte(`title: T
+defaults:
+  rules: [{ match: {}, status: { id: ok, label: "✅" } }]
+groups:
+  - name: G
+    tiles:
+      - name: T
+        generate: { command: make, env: { "A=B": x } }
+        slots:
+          - { name: web, check: { target: "https://example.com", stdin: x } }
+`))
+	for _, want := range []string{
+		"check.env, check.clear_env, check.workdir and check.stdin are only supported for command checks",
+		`generate.env: invalid variable name "A=B"`,
+	} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("error = %v, want to contain %q", err, want)
+		}
+	}
+}
+
+func TestByteSize(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    ByteSize
+		wantErr bool
+	}{
+		{in: "1048576", want: 1 << 20},
+		{in: "64K", want: 64 << 10},
+		{in: "512 MiB", want: 512 << 20},
+		{in: "2GB", want: 2 << 30},
+		{in: "1t", want: 1 << 40},
+		{in: "12X", wantErr: true},
+		{in: "-1", wantErr: true},
+		{in: "99999999999T", wantErr: true},
+	}
+	for _, tt := range tests {
+		var got struct {
+			Size ByteSize `yaml:"size"`
+		}
+		err := yaml.Unmarshal([]byte("size: "+tt.in), &got)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%q: err = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got.Size != tt.want {
+			t.Errorf("%q = %d, want %d", tt.in, got.Size, tt.want)
+		}
+	}
+}
+
+func TestParse_Interval(t *testing.T) {
+	cfg, err := Parse([]byte(`title: T
+defaults:
+  rules: [{ match: {}, status: { id: ok, label: "✅" } }]
+groups:
+  - name: G
+    defaults: { interval: 1h }
+    tiles:
+      - name: T
+        slots:
+          - { name: inherited, check: "true" }
+          - { name: own, check: { target: "true", interval: 5m } }
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	slots := cfg.Groups[0].Tiles[0].Slots
+	if got := slots[0].Check.Interval.Duration; got != time.Hour {
+		t.Errorf("inherited interval = %v, want 1h", got)
+	}
+	if got := slots[1].Check.Interval.Duration; got != 5*time.Minute {
+		t.Errorf("own interval = %v, want 5m", got)
+	}
+
+	_, err = Parse([]byte(`title: T
+defaults:
+  rules: [{ match: {}, status: { id: ok, label: "✅" } }]
+groups:
+  - name: G
+    discover: { target: "true", interval: 1h }
+    tiles:
+      - name: T
+        slots: [{ name: s, check: { target: "true", interval: -1m } }]
+`))
+	for _, want := range []string{"discover.interval is not supported", "check.interval must not be negative"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("error = %v, want to contain %q", err, want)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// validateDependencies checks the depends_on paths of the slots in the
+// tiles for which check returns true. Each must name another slot in a tile
+// for which known returns true, and no slot may depend on itself through a
+// chain of dependencies.
+func (c *Config) validateDependencies(errs *Errors, known, check func(gi int, t *Tile) bool) {
+	slots := make(map[string]*Slot)
+	for gi := range c.Groups {
+		g := &c.Groups[gi]
+		for ti := range g.Tiles {
+			if !known(gi, &g.Tiles[ti]) {
+				continue
+			}
+			for si := range g.Tiles[ti].Slots {
+				path := SlotPath(g.Name, g.Tiles[ti].Name, g.Tiles[ti].Slots[si].Name)
+				if _, ok := slots[path]; !ok {
+					slots[path] = &g.Tiles[ti].Slots[si]
+				}
+			}
+		}
+	}
+
+	// Depth-first search for cycles: a dependency that is still on the
+	// stack closes one.
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[string]int)
+	var stack []string
+	var cycle func(path string) []string
+	cycle = func(path string) []string {
+		switch state[path] {
+		case visiting:
+			i := len(stack) - 1
+			for stack[i] != path {
+				i--
+			}
+			return append(stack[i:], path)
+		case visited:
+			return nil
+		}
+		state[path] = visiting
+		stack = append(stack, path)
+		defer func() {
+			state[path] = visited
+			stack = stack[:len(stack)-1]
+		}()
+		for _, dep := range slots[path].DependsOn {
+			if _, ok := slots[dep]; ok {
+				if found := cycle(dep); found != nil {
+					return found
+				}
+			}
+		}
+		return nil
+	}
+
+	for gi := range c.Groups {
+		g := &c.Groups[gi]
+		for ti := range g.Tiles {
+			t := &g.Tiles[ti]
+			if !check(gi, t) {
+				continue
+			}
+			for si, s := range t.Slots {
+				if len(s.DependsOn) == 0 {
+					continue
+				}
+				prefix := fmt.Sprintf("group[%d] %q, tile[%d] %q, slot[%d] %q", gi, g.Name, ti, t.Name, si, s.Name)
+				path := SlotPath(g.Name, t.Name, s.Name)
+				for _, dep := range s.DependsOn {
+					switch _, ok := slots[dep]; {
+					case dep == path:
+						errs.add(s.Pos, "%s: depends_on: a slot cannot depend on itself", prefix)
+					case !ok:
+						errs.add(s.Pos, "%s: depends_on: no slot %q (use \"Group/Tile/slot\")", prefix, dep)
+					}
+				}
+				if slots[path] != &t.Slots[si] {
+					continue // a duplicate path; dependencies resolve to the first
+				}
+				if found := cycle(path); len(found) > 2 {
+					errs.add(s.Pos, "%s: depends_on: cycle %s", prefix, strings.Join(found, " → "))
+				}
+			}
+		}
+	}
+}
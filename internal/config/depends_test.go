@@ -0,0 +1,111 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+const dependsYAML = `title: T
+defaults:
+  rules:
+    - match: {}
+      status: { id: ok, label: "✅" }
+groups:
+  - name: Network
+    tiles:
+      - name: Gateway
+        slots: [{ name: ping, check: "true" }]
+  - name: Web
+    tiles:
+      - name: Blog
+        slots:
+          - { name: http, check: "true", depends_on: Network/Gateway/ping }
+          - { name: tls, check: "true", depends_on: [Network/Gateway/ping, Web/Blog/http] }
+  - name: Services
+    discover: "true"
+`
+
+func TestParse_DependsOn(t *testing.T) {
+	cfg, err := ParseStrict([]byte(dependsYAML))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := cfg.Groups[1].Tiles[0].Slots[1].DependsOn; len(got) != 2 || got[1] != "Web/Blog/http" {
+		t.Errorf("depends_on = %v", got)
+	}
+}
+
+func TestParse_DependsOnErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		slots   string
+		wantErr string
+	}{
+		{
+			name:    "unknown slot",
+			slots:   `[{ name: a, check: "true", depends_on: Net/GW/ping }]`,
+			wantErr: `line 9, column 12: group[0] "G", tile[0] "T", slot[0] "a": depends_on: no slot "Net/GW/ping" (use "Group/Tile/slot")`,
+		},
+		{
+			name:    "itself",
+			slots:   `[{ name: a, check: "true", depends_on: G/T/a }]`,
+			wantErr: `slot[0] "a": depends_on: a slot cannot depend on itself`,
+		},
+		{
+			name: "cycle",
+			slots: `
+          - { name: a, check: "true", depends_on: G/T/b }
+          - { name: b, check: "true", depends_on: G/T/c }
+          - { name: c, check: "true", depends_on: G/T/a }`,
+			wantErr: `line 9, column 13: group[0] "G", tile[0] "T", slot[0] "a": depends_on: cycle G/T/a → G/T/b → G/T/c → G/T/a`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(`title: T
+defaults:
+  rules: [{ match: {}, status: { id: ok, label: "✅" } }]
+groups:
+  - name: G
+    tiles:
+      - name: T
+        slots:
+          ` + strings.TrimSpace(tt.slots) + `
+`))
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %q, want to contain %q", err.Error(), tt.wantErr)
+			}
+			if strings.Count(err.Error(), "depends_on") != 1 {
+				t.Errorf("error = %q, want the problem reported once", err.Error())
+			}
+		})
+	}
+}
+
+func TestAddDiscovered_DependsOn(t *testing.T) {
+	cfg, err := Parse([]byte(dependsYAML))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	err = cfg.AddDiscovered(2, []byte(`[{ name: A, slots: [{ name: up, check: "true", depends_on: Services/B/up }] },
+{ name: B, slots: [{ name: up, check: "true", depends_on: Services/A/up }] }]`))
+	if err == nil || !strings.Contains(err.Error(), "depends_on: cycle Services/A/up → Services/B/up → Services/A/up") {
+		t.Errorf("err = %v, want a cycle", err)
+	}
+	if n := len(cfg.Groups[2].Tiles); n != 0 {
+		t.Fatalf("%d tiles added despite the error", n)
+	}
+
+	err = cfg.AddDiscovered(2, []byte(`[{ name: A, slots: [{ name: up, check: "true", depends_on: [Network/Gateway/ping, Services/B/up] }] },
+{ name: B, slots: [{ name: up, check: "true" }] }]`))
+	if err != nil {
+		t.Fatalf("AddDiscovered: %v", err)
+	}
+	if !cfg.Groups[2].Tiles[0].Discovered {
+		t.Error("discovered tiles should be marked")
+	}
+}
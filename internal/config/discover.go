@@ -0,0 +1,74 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// AddDiscovered adds the tiles in the output of group gi's discover check
+// to the group. The output is YAML (or JSON) holding either a list of tiles
+// or a mapping with a "tiles" list, in the same form as in a config file.
+// Slots get the group's and the global defaults.
+//
+// The tiles are validated like configured ones. Their slots may depend on
+// configured slots and on slots discovered for the same group. If there is
+// any problem, none are added and all problems are returned as Errors. Positions refer to
+// lines of the output, which is named `discover "<group>"`.
+func (c *Config) AddDiscovered(gi int, data []byte) error {
+	g := &c.Groups[gi]
+	source := fmt.Sprintf("discover %q", g.Name)
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return decodeError(err, source)
+	}
+	if len(doc.Content) == 0 {
+		return nil
+	}
+	list := doc.Content[0]
+	if list.Kind == yaml.MappingNode {
+		list = mappingValue(list, "tiles")
+	}
+	if list == nil || list.Kind != yaml.SequenceNode {
+		return &Error{Pos: Pos{File: source}, Msg: "discovered tiles must be a list, or a mapping with a tiles list"}
+	}
+
+	var tiles []Tile
+	var errs Errors
+	if err := list.Decode(&tiles); err != nil {
+		var te *yaml.TypeError
+		if !errors.As(err, &te) {
+			return decodeError(err, source)
+		}
+		errs = typeErrors(te, source)
+	}
+	for ti, tn := range items(list, len(tiles)) {
+		setTilePositions(&tiles[ti], tn, source)
+	}
+
+	n := len(g.Tiles)
+	for ti := range tiles {
+		t := &tiles[ti]
+		t.Discovered = true
+		c.useTileRuleSets(&errs, gi, g.Name, n+ti, t)
+		c.applyDefaults(g, t)
+		c.resolveTileWorkdirs(t)
+		validateTile(&errs, gi, g.Name, n+ti, *t)
+		checkTileWorkdirs(&errs, gi, g.Name, n+ti, *t)
+		c.interpolateTile(&errs, g.Name, t)
+	}
+
+	g.Tiles = append(g.Tiles, tiles...)
+	c.validateDependencies(&errs,
+		func(i int, t *Tile) bool { return !t.Discovered || i == gi },
+		func(i int, t *Tile) bool { return t.Discovered && i == gi },
+	)
+	if len(errs) > 0 {
+		g.Tiles = g.Tiles[:n]
+		errs.sort()
+		return errs
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+const discoverYAML = `title: T
+defaults:
+  rules:
+    - match: {}
+      status: { id: ok, label: "✅" }
+groups:
+  - name: Services
+    discover: https://registry.example.com/tiles.json
+`
+
+func TestAddDiscovered(t *testing.T) {
+	cfg, err := ParseStrict([]byte(discoverYAML))
+	if err != nil {
+		t.Fatalf("a group with discover needs no tiles: %v", err)
+	}
+	if d := cfg.Groups[0].Discover; d.Type != "http" || d.Pos.Line != 8 {
+		t.Errorf("discover = %+v, want inferred http check at line 8", d)
+	}
+
+	for _, data := range []string{
+		`[{"name": "API", "slots": [{"name": "up", "check": "https://api.example.com/"}]}]`,
+		"tiles:\n  - name: Worker\n    slots: [{ name: up, check: \"true\" }]\n",
+	} {
+		if err := cfg.AddDiscovered(0, []byte(data)); err != nil {
+			t.Fatalf("AddDiscovered(%q): %v", data, err)
+		}
+	}
+	tiles := cfg.Groups[0].Tiles
+	if len(tiles) != 2 || tiles[0].Name != "API" || tiles[1].Name != "Worker" {
+		t.Fatalf("tiles = %+v, want API then Worker", tiles)
+	}
+	if s := tiles[0].Slots[0]; s.Check.Type != "http" || s.RulesFrom != "defaults" {
+		t.Errorf("API slot = %+v, want inferred http check with default rules", s)
+	}
+	if got := tiles[1].Pos.String(); got != `discover "Services":2:5` {
+		t.Errorf("worker position = %s", got)
+	}
+}
+
+func TestAddDiscovered_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{
+			name:    "not a list",
+			data:    `{"name": "API"}`,
+			wantErr: "discovered tiles must be a list",
+		},
+		{
+			name:    "syntax",
+			data:    `[{"name": "API"`,
+			wantErr: `discover "Services"`,
+		},
+		{
+			name:    "validation",
+			data:    "- name: API\n  slots: [{ name: up, check: { type: ftp, target: x } }]\n",
+			wantErr: `discover "Services":2:30: group[0] "Services", tile[0] "API", slot[0] "up": check.type must be`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := Parse([]byte(discoverYAML))
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = cfg.AddDiscovered(0, []byte(tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want to contain %q", err, tt.wantErr)
+			}
+			if len(cfg.Groups[0].Tiles) != 0 {
+				t.Errorf("tiles = %+v, want none added", cfg.Groups[0].Tiles)
+			}
+		})
+	}
+}
+
+func TestParse_DiscoverValidation(t *testing.T) {
+	_, err := Parse([]byte(`title: T
+groups:
+  - name: G
+    discover: { type: ftp, target: x }
+`))
+	if err == nil || !strings.Contains(err.Error(), `line 4, column 15: group[0] "G": discover.type must be`) {
+		t.Errorf("err = %v, want discover.type error", err)
+	}
+}
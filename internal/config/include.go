@@ -0,0 +1,287 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// loadFiles loads the config at path together with everything it includes
+// and merges it into a single, not yet validated Config. If path is a
+// directory, every *.yaml and *.yml file in it is loaded in lexical order.
+// It returns the directory that relative paths in the merged config are
+// resolved against.
+//
+// Merging is deterministic: files are processed depth-first in the order
+// they are listed (glob matches in lexical order), groups with the same name
+// are combined by appending their tiles, css lists are concatenated, and
+// every other top-level setting, such as each rule set, may be set by at most
+// one file. YAML anchors stay local to the file that defines them; rule sets
+// are how files share rules.
+func loadFiles(path string, strict bool) (*Config, string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, "", fmt.Errorf("reading config file: %w", err)
+	}
+
+	l := &loader{
+		merged: &Config{keyPos: make(map[string]Pos)},
+		setBy:  make(map[string]Pos),
+		seen:   make(map[string]bool),
+		strict: strict,
+	}
+
+	if !info.IsDir() {
+		l.root = filepath.Dir(path)
+		if err := l.load(path); err != nil {
+			return nil, "", err
+		}
+		return l.merged, l.root, nil
+	}
+
+	l.root = path
+	files, err := yamlFiles(path)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(files) == 0 {
+		return nil, "", fmt.Errorf("config: no *.yaml or *.yml files in %s", path)
+	}
+	for _, f := range files {
+		if err := l.load(f); err != nil {
+			return nil, "", err
+		}
+	}
+	return l.merged, l.root, nil
+}
+
+// loader accumulates config files into one merged Config.
+type loader struct {
+	root   string          // directory relative paths are rebased onto
+	merged *Config         // result of all files loaded so far
+	setBy  map[string]Pos  // top-level setting -> where it was set
+	seen   map[string]bool // absolute paths of files already loaded
+	strict bool            // reject unknown keys, see LoadStrict
+}
+
+// load decodes a single file, merges it and then follows its includes.
+// Files that were already loaded (e.g. a glob matching the including file)
+// are skipped, which also breaks include cycles.
+func (l *loader) load(path string) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("resolving %s: %w", path, err)
+	}
+	if l.seen[abs] {
+		return nil
+	}
+	l.seen[abs] = true
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading config file: %w", err)
+	}
+	cfg, err := decode(data, path, l.strict)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(path)
+	l.rebase(cfg, dir)
+	l.merge(cfg, path)
+
+	for _, inc := range cfg.Include {
+		files, err := expandInclude(dir, inc)
+		if err != nil {
+			pos := cfg.at("include")
+			pos.File = path
+			l.merged.problems.add(pos, "include %q: %v", inc, err)
+			continue
+		}
+		for _, f := range files {
+			if err := l.load(f); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// merge adds the settings of one file to the merged config. Conflicts are
+// recorded as problems for validate to report.
+func (l *loader) merge(cfg *Config, file string) {
+	m := l.merged
+
+	setOnce := func(field string, isSet bool, apply func()) {
+		if !isSet {
+			return
+		}
+		pos := cfg.at(field)
+		if pos.IsZero() {
+			pos.File = file
+		}
+		if prev, ok := l.setBy[field]; ok {
+			m.problems.add(pos, "%s is already set in %s", field, prev)
+			return
+		}
+		l.setBy[field] = pos
+		m.keyPos[field] = pos
+		apply()
+	}
+	setOnce("title", cfg.Title != "", func() { m.Title = cfg.Title })
+	setOnce("theme", cfg.Theme != "", func() { m.Theme = cfg.Theme })
+	setOnce("template", cfg.Template != "", func() { m.Template = cfg.Template })
+	setOnce("defaults", cfg.Defaults != nil, func() { m.Defaults = cfg.Defaults })
+	setOnce("severity", cfg.Severity != nil, func() { m.Severity = cfg.Severity })
+	setOnce("refresh", cfg.Refresh.Duration != 0, func() { m.Refresh = cfg.Refresh })
+	setOnce("notify", cfg.Notify != nil, func() { m.Notify = cfg.Notify })
+	setOnce("on_change", cfg.OnChange != "", func() { m.OnChange = cfg.OnChange })
+
+	for _, name := range slices.Sorted(maps.Keys(cfg.RuleSets)) {
+		setOnce("rule_sets."+name, true, func() {
+			if m.RuleSets == nil {
+				m.RuleSets = make(map[string][]Rule)
+			}
+			m.RuleSets[name] = cfg.RuleSets[name]
+		})
+	}
+	for _, name := range slices.Sorted(maps.Keys(cfg.Themes)) {
+		setOnce("themes."+name, true, func() {
+			if m.Themes == nil {
+				m.Themes = make(map[string]Theme)
+			}
+			m.Themes[name] = cfg.Themes[name]
+		})
+	}
+
+	if _, ok := m.keyPos[""]; !ok {
+		m.keyPos[""] = cfg.at("")
+	}
+	m.CSS = append(m.CSS, cfg.CSS...)
+	m.problems = append(m.problems, cfg.problems...)
+
+	if _, ok := m.keyPos["groups"]; !ok && len(cfg.Groups) > 0 {
+		m.keyPos["groups"] = cfg.at("groups")
+	}
+	for _, g := range cfg.Groups {
+		if existing := m.group(g.Name); existing != nil {
+			existing.Tiles = append(existing.Tiles, g.Tiles...)
+			existing.Maintenance = append(existing.Maintenance, g.Maintenance...)
+			if g.Discover != nil && existing.Discover != nil {
+				m.problems.add(g.Discover.Pos, "group %q: discover is already set in %s", g.Name, existing.Discover.Pos)
+			} else if g.Discover != nil {
+				existing.Discover = g.Discover
+			}
+			if g.Defaults != nil && existing.Defaults != nil {
+				m.problems.add(g.Pos, "group %q: defaults can only be set in one of the files declaring the group", g.Name)
+			} else if g.Defaults != nil {
+				existing.Defaults = g.Defaults
+			}
+			continue
+		}
+		m.Groups = append(m.Groups, g)
+	}
+}
+
+// group returns the merged group with the given name, or nil.
+func (c *Config) group(name string) *Group {
+	if name == "" {
+		return nil
+	}
+	for i := range c.Groups {
+		if c.Groups[i].Name == name {
+			return &c.Groups[i]
+		}
+	}
+	return nil
+}
+
+// rebase rewrites relative file references of a config loaded from dir so
+// they are relative to the loader's root directory instead.
+func (l *loader) rebase(cfg *Config, dir string) {
+	cfg.Template = rebasePath(cfg.Template, dir, l.root)
+	for i := range cfg.CSS {
+		cfg.CSS[i] = rebasePath(cfg.CSS[i], dir, l.root)
+	}
+	for gi := range cfg.Groups {
+		g := &cfg.Groups[gi]
+		if g.Discover != nil {
+			g.Discover.Workdir = rebasePath(g.Discover.Workdir, dir, l.root)
+		}
+		for ti := range g.Tiles {
+			t := &g.Tiles[ti]
+			t.Icon = rebasePath(t.Icon, dir, l.root)
+			if t.Banner != nil {
+				t.Banner.Src = rebasePath(t.Banner.Src, dir, l.root)
+			}
+			if t.Generate != nil {
+				t.Generate.Workdir = rebasePath(t.Generate.Workdir, dir, l.root)
+			}
+			for si := range t.Slots {
+				t.Slots[si].Check.Workdir = rebasePath(t.Slots[si].Check.Workdir, dir, l.root)
+			}
+		}
+	}
+}
+
+// rebasePath makes a path relative to from relative to to. Empty values,
+// absolute paths and URLs are returned unchanged.
+func rebasePath(p, from, to string) string {
+	if p == "" || filepath.IsAbs(p) || strings.HasPrefix(p, "http://") || strings.HasPrefix(p, "https://") {
+		return p
+	}
+	if filepath.Clean(from) == filepath.Clean(to) {
+		return p
+	}
+	rel, err := filepath.Rel(to, filepath.Join(from, p))
+	if err != nil {
+		return filepath.Join(from, p)
+	}
+	return rel
+}
+
+// expandInclude resolves an include entry relative to dir. Entries with glob
+// characters may match nothing; plain paths must exist. Directories expand to
+// the YAML files they contain.
+func expandInclude(dir, pattern string) ([]string, error) {
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(dir, pattern)
+	}
+
+	if !strings.ContainsAny(pattern, "*?[") {
+		info, err := os.Stat(pattern)
+		if err != nil {
+			return nil, err
+		}
+		if info.IsDir() {
+			return yamlFiles(pattern)
+		}
+		return []string{pattern}, nil
+	}
+
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return matches, nil
+}
+
+// yamlFiles lists the *.yaml and *.yml files in dir in lexical order.
+func yamlFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("reading config directory: %w", err)
+	}
+	var files []string
+	for _, e := range entries {
+		ext := filepath.Ext(e.Name())
+		if e.IsDir() || (ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+		files = append(files, filepath.Join(dir, e.Name()))
+	}
+	return files, nil
+}
@@ -0,0 +1,383 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTree creates files (with parent directories) below dir.
+func writeTree(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+const includeRoot = `
+title: Main
+include:
+  - "teams/*.yaml"
+defaults:
+  rules:
+    - match: {}
+      status: { id: ok, label: "✅" }
+groups:
+  - name: Network
+    tiles:
+      - name: Gateway
+        slots:
+          - { name: ping, check: "ping gw" }
+`
+
+func TestLoad_IncludeGlobMergesGroups(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"config.yaml": includeRoot,
+		"teams/b.yaml": `
+groups:
+  - name: Storage
+    tiles:
+      - name: NAS
+        icon: icons/nas.png
+        slots:
+          - { name: up, check: "true" }
+`,
+		"teams/a.yaml": `
+_anchors:
+  local: &local
+    - match: {}
+      status: { id: local, label: "L" }
+groups:
+  - name: Network
+    tiles:
+      - name: Router
+        slots:
+          - { name: ping, check: "ping router", rules: *local }
+`,
+	})
+
+	cfg, err := Load(filepath.Join(dir, "config.yaml"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(cfg.Groups) != 2 || cfg.Groups[0].Name != "Network" || cfg.Groups[1].Name != "Storage" {
+		t.Fatalf("groups = %+v, want Network then Storage", cfg.Groups)
+	}
+	net := cfg.Groups[0]
+	if len(net.Tiles) != 2 || net.Tiles[0].Name != "Gateway" || net.Tiles[1].Name != "Router" {
+		t.Fatalf("network tiles = %+v, want Gateway then Router", net.Tiles)
+	}
+	if got := net.Tiles[1].Pos; got.File != filepath.Join(dir, "teams", "a.yaml") || got.Line != 9 {
+		t.Errorf("router position = %v, want line 9 of teams/a.yaml", got)
+	}
+	if got := net.Tiles[1].Slots[0].Rules[0].Status.ID; got != "local" {
+		t.Errorf("router rules = %q, want local anchor rules", got)
+	}
+	if got := cfg.Groups[1].Tiles[0].Slots[0].RulesFrom; got != "defaults" {
+		t.Errorf("NAS rules from %q, want defaults from the root file", got)
+	}
+	if got, want := cfg.Groups[1].Tiles[0].Icon, filepath.Join("teams", "icons", "nas.png"); got != want {
+		t.Errorf("icon = %q, want %q (relative to the root config)", got, want)
+	}
+	if cfg.Dir != dir {
+		t.Errorf("dir = %q, want %q", cfg.Dir, dir)
+	}
+}
+
+func TestLoad_ConfDirectory(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"conf.d/10-base.yaml": "title: From dir\non_change: page-me\ndefaults:\n  rules: [{ match: {}, status: { id: ok, label: \"✅\" } }]\n",
+		"conf.d/20-web.yml":   "groups: [{ name: Web, tiles: [{ name: Site, slots: [{ name: up, check: \"true\" }] }] }]\n",
+		"conf.d/README.md":    "ignored",
+	})
+
+	cfg, err := Load(filepath.Join(dir, "conf.d"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Title != "From dir" || len(cfg.Groups) != 1 || cfg.Groups[0].Name != "Web" {
+		t.Errorf("cfg = %+v", cfg)
+	}
+	if cfg.Dir != filepath.Join(dir, "conf.d") {
+		t.Errorf("dir = %q, want the conf.d directory", cfg.Dir)
+	}
+	if got := cfg.Groups[0].Tiles[0].Slots[0].OnChange; got != "page-me" {
+		t.Errorf("on_change = %q, want the one from 10-base.yaml", got)
+	}
+}
+
+func TestLoad_IncludeErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		wantErr []string
+	}{
+		{
+			name: "setting defined twice",
+			files: map[string]string{
+				"config.yaml":    includeRoot,
+				"teams/dup.yaml": "title: Other\n",
+			},
+			wantErr: []string{"dup.yaml:1:8: title is already set in", "config.yaml:2:8"},
+		},
+		{
+			name: "missing plain include",
+			files: map[string]string{
+				"config.yaml": "title: T\ninclude: nope.yaml\n",
+			},
+			wantErr: []string{`include "nope.yaml"`},
+		},
+		{
+			name: "validation names the file",
+			files: map[string]string{
+				"config.yaml":  includeRoot,
+				"teams/x.yaml": "groups: [{ name: Network, tiles: [{ name: Bad, slots: [{ name: s, check: { type: ftp, target: x } }] }] }]\n",
+			},
+			wantErr: []string{"x.yaml:1:74: group[0] \"Network\", tile[1] \"Bad\"", "check.type must be"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeTree(t, dir, tt.files)
+			_, err := Load(filepath.Join(dir, "config.yaml"))
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error = %q, want to contain %q", err.Error(), want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoad_IncludeSelfAndCycles(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"config.yaml": includeRoot + "\n# matches itself\n",
+		"teams/a.yaml": `
+include: ../config.yaml
+groups: [{ name: A, tiles: [{ name: T, slots: [{ name: s, check: "true" }] }] }]
+`,
+	})
+	cfg, err := Load(filepath.Join(dir, "config.yaml"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cfg.Groups) != 2 {
+		t.Errorf("len(groups) = %d, want 2 (each file loaded once)", len(cfg.Groups))
+	}
+}
+
+func TestParse_RejectsInclude(t *testing.T) {
+	_, err := Parse([]byte("title: T\ninclude: other.yaml\n"))
+	if err == nil || !strings.Contains(err.Error(), "include") {
+		t.Errorf("err = %v, want include error", err)
+	}
+}
+
+func TestLoad_Workdir(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"config.yaml": `
+title: Main
+include: [teams/storage.yaml]
+defaults:
+  rules: [{ match: {}, status: { id: ok, label: "✅" } }]
+groups:
+  - name: Network
+    tiles:
+      - name: Gateway
+        generate: { command: make, workdir: graphs }
+        slots:
+          - { name: ping, check: { target: ./ping.sh, workdir: scripts } }
+          - { name: abs, check: { target: "true", workdir: / } }
+`,
+		"teams/storage.yaml": `
+groups:
+  - name: Storage
+    discover: { target: ./list.sh, workdir: . }
+    tiles:
+      - name: NAS
+        slots:
+          - { name: up, check: { target: "true", workdir: nas } }
+`,
+		"graphs/.keep":    "",
+		"scripts/ping.sh": "",
+		"teams/nas/.keep": "",
+		"new/.keep":       "",
+	})
+
+	cfg, err := Load(filepath.Join(dir, "config.yaml"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	gw := cfg.Groups[0].Tiles[0]
+	tests := []struct {
+		name, got, want string
+	}{
+		{"generate", gw.Generate.Workdir, filepath.Join(dir, "graphs")},
+		{"check", gw.Slots[0].Check.Workdir, filepath.Join(dir, "scripts")},
+		{"absolute", gw.Slots[1].Check.Workdir, "/"},
+		{"discover", cfg.Groups[1].Discover.Workdir, filepath.Join(dir, "teams")},
+		{"included", cfg.Groups[1].Tiles[0].Slots[0].Check.Workdir, filepath.Join(dir, "teams", "nas")},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s workdir = %q, want %q", tt.name, tt.got, tt.want)
+		}
+	}
+
+	if err := cfg.AddDiscovered(1, []byte(`[{ name: New, slots: [{ name: up, check: { target: "true", workdir: new } }] }]`)); err != nil {
+		t.Fatalf("AddDiscovered: %v", err)
+	}
+	if got, want := cfg.Groups[1].Tiles[1].Slots[0].Check.Workdir, filepath.Join(dir, "new"); got != want {
+		t.Errorf("discovered workdir = %q, want %q", got, want)
+	}
+
+	err = cfg.AddDiscovered(1, []byte(`[{ name: Gone, slots: [{ name: up, check: { target: "true", workdir: gone } }] }]`))
+	if err == nil || !strings.Contains(err.Error(), `tile[2] "Gone", slot[0] "up": check.workdir: stat `+filepath.Join(dir, "gone")) {
+		t.Errorf("err = %v, want the missing discovered workdir", err)
+	}
+}
+
+func TestLoad_MissingWorkdir(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"config.yaml": `
+title: Main
+defaults:
+  rules: [{ match: {}, status: { id: ok, label: "✅" } }]
+groups:
+  - name: Network
+    discover: { target: ./list.sh, workdir: scripts/list.sh }
+    tiles:
+      - name: Gateway
+        generate: { command: make, workdir: graphs }
+        slots:
+          - { name: ping, check: { target: ./ping.sh, workdir: scripts } }
+`,
+		"scripts/list.sh": "",
+	})
+
+	_, err := Load(filepath.Join(dir, "config.yaml"))
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	for _, want := range []string{
+		`config.yaml:7:15: group[0] "Network": discover.workdir: ` + filepath.Join(dir, "scripts", "list.sh") + " is not a directory",
+		`config.yaml:9:9: group[0] "Network", tile[0] "Gateway": generate.workdir: stat ` + filepath.Join(dir, "graphs"),
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("err = %v, want to contain %q", err, want)
+		}
+	}
+	if strings.Contains(err.Error(), "check.workdir") {
+		t.Errorf("err = %v, want no error for the existing scripts directory", err)
+	}
+}
+
+func TestLoad_RuleSets(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"config.yaml": `
+title: Main
+include: [teams/*.yaml]
+rule_sets:
+  exit-code:
+    - match: { code: 0 }
+      status: { id: ok, label: "✅" }
+    - match: {}
+      status: { id: down, label: "❌" }
+groups:
+  - name: Network
+    tiles:
+      - name: Gateway
+        slots:
+          - { name: ping, check: "ping gw", rule_set: http-status }
+`,
+		"teams/web.yaml": `
+rule_sets:
+  http-status:
+    - match: { code: 200 }
+      status: { id: ok, label: "✅" }
+groups:
+  - name: Web
+    defaults: { rule_set: exit-code }
+    tiles:
+      - name: Blog
+        slots:
+          - { name: up, check: "true" }
+`,
+	})
+
+	cfg, err := LoadStrict(filepath.Join(dir, "config.yaml"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ping := cfg.Groups[0].Tiles[0].Slots[0]
+	if len(ping.Rules) != 1 || ping.Rules[0].Status.ID != "ok" || ping.RulesFrom != `rule set "http-status"` {
+		t.Errorf("ping rules = %+v from %q, want the rule set of the included file", ping.Rules, ping.RulesFrom)
+	}
+	if got := ping.Rules[0].Pos; got.File != filepath.Join(dir, "teams", "web.yaml") {
+		t.Errorf("rule position = %v, want the included file", got)
+	}
+	up := cfg.Groups[1].Tiles[0].Slots[0]
+	if len(up.Rules) != 2 || up.RulesFrom != "group defaults" {
+		t.Errorf("up rules = %+v from %q, want the root file's rule set through the group defaults", up.Rules, up.RulesFrom)
+	}
+}
+
+func TestLoad_RuleSetErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"config.yaml": `
+title: Main
+include: [more.yaml]
+rule_sets:
+  shared: [{ match: {}, status: { id: ok, label: "✅" } }]
+  empty: []
+groups:
+  - name: G
+    tiles:
+      - name: T
+        slots:
+          - { name: unknown, check: "true", rule_set: sharde }
+          - name: both
+            check: "true"
+            rule_set: shared
+            rules: [{ match: {}, status: { id: ok, label: "✅" } }]
+`,
+		"more.yaml": `
+rule_sets:
+  shared: [{ match: {}, status: { id: other, label: "?" } }]
+`,
+	})
+
+	_, err := Load(filepath.Join(dir, "config.yaml"))
+	for _, want := range []string{
+		`slot[0] "unknown": rule_set: no rule set "sharde" under rule_sets`,
+		`slot[1] "both": rules and rule_set cannot both be set`,
+		`rule_sets "empty": at least one rule is required`,
+		`rule_sets.shared is already set in ` + filepath.Join(dir, "config.yaml"),
+	} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("error = %v, want to contain %q", err, want)
+		}
+	}
+	if n := strings.Count(err.Error(), "at least one rule is required"); n != 1 {
+		t.Errorf("error = %v, want only the empty rule set to lack rules", err)
+	}
+}
@@ -0,0 +1,189 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// minSecretLen is the shortest secret value that Redact replaces.
+// Shorter values (ports, flags, "1") would mangle unrelated output.
+const minSecretLen = 4
+
+// placeholder matches ${NAME}, ${secret:NAME}, ${file:PATH} and the $${
+// escape. Other ${...} forms, such as bash's ${var:-default}, are left alone.
+var placeholder = regexp.MustCompile(`\$\$\{|\$\{([A-Za-z_][A-Za-z0-9_]*|secret:[A-Za-z_][A-Za-z0-9_]*|file:[^}]+)\}`)
+
+// interpolate substitutes ${ENV_VAR}, ${secret:ENV_VAR} and ${file:/path}
+// references in the target, headers, body, env and stdin of every check,
+// including discover checks, and in the url, headers and body of webhooks
+// and the email credentials. Files are read whole, with a single trailing
+// newline removed. "$${" produces a literal "${". The values of secret and
+// file references are remembered for Redact.
+func (c *Config) interpolate(errs *Errors) {
+	for gi := range c.Groups {
+		g := &c.Groups[gi]
+		if g.Discover != nil {
+			c.interpolateCheck(errs, fmt.Sprintf("group %q", g.Name), "discover", g.Discover)
+		}
+		for ti := range g.Tiles {
+			c.interpolateTile(errs, g.Name, &g.Tiles[ti])
+		}
+	}
+	if c.Notify != nil {
+		for i := range c.Notify.Webhooks {
+			w := &c.Notify.Webhooks[i]
+			prefix := fmt.Sprintf("notify.webhooks[%d]", i)
+			var err error
+			if w.URL, err = c.expand(w.URL); err != nil {
+				errs.add(w.Pos, "%s: url: %v", prefix, err)
+			}
+			for _, k := range slices.Sorted(maps.Keys(w.Headers)) {
+				if w.Headers[k], err = c.expand(w.Headers[k]); err != nil {
+					errs.add(w.Pos, "%s: headers %q: %v", prefix, k, err)
+				}
+			}
+			if w.Body, err = c.expand(w.Body); err != nil {
+				errs.add(w.Pos, "%s: body: %v", prefix, err)
+			}
+		}
+		if e := c.Notify.Email; e != nil {
+			var err error
+			if e.Username, err = c.expand(e.Username); err != nil {
+				errs.add(e.Pos, "notify.email: username: %v", err)
+			}
+			if e.Password, err = c.expand(e.Password); err != nil {
+				errs.add(e.Pos, "notify.email: password: %v", err)
+			}
+		}
+	}
+}
+
+func (c *Config) interpolateTile(errs *Errors, group string, t *Tile) {
+	if t.Generate != nil {
+		c.interpolateProcess(errs, t.Pos, fmt.Sprintf("group %q, tile %q", group, t.Name), "generate", &t.Generate.Process)
+	}
+	for si := range t.Slots {
+		s := &t.Slots[si]
+		c.interpolateCheck(errs, fmt.Sprintf("group %q, tile %q, slot %q", group, t.Name, s.Name), "check", &s.Check)
+	}
+}
+
+func (c *Config) interpolateCheck(errs *Errors, prefix, key string, chk *Check) {
+	var err error
+	expandTarget := c.expand
+	if chk.Type == "command" {
+		expandTarget = c.expandCommand
+	}
+	if chk.Target, err = expandTarget(chk.Target); err != nil {
+		errs.add(chk.Pos, "%s: %s.target: %v", prefix, key, err)
+	}
+	for i := range chk.Args {
+		if chk.Args[i], err = c.expand(chk.Args[i]); err != nil {
+			errs.add(chk.Pos, "%s: %s.target[%d]: %v", prefix, key, i, err)
+		}
+	}
+	for _, k := range slices.Sorted(maps.Keys(chk.Headers)) {
+		if chk.Headers[k], err = c.expand(chk.Headers[k]); err != nil {
+			errs.add(chk.Pos, "%s: %s.headers %q: %v", prefix, key, k, err)
+		}
+	}
+	if chk.Body, err = c.expand(chk.Body); err != nil {
+		errs.add(chk.Pos, "%s: %s.body: %v", prefix, key, err)
+	}
+	c.interpolateProcess(errs, chk.Pos, prefix, key, &chk.Process)
+}
+
+// interpolateProcess expands the env values and stdin of key. The command
+// itself is left to interpolateCheck; generate commands are not expanded.
+func (c *Config) interpolateProcess(errs *Errors, pos Pos, prefix, key string, p *Process) {
+	var err error
+	for _, k := range slices.Sorted(maps.Keys(p.Env)) {
+		if p.Env[k], err = c.expand(p.Env[k]); err != nil {
+			errs.add(pos, "%s: %s.env %q: %v", prefix, key, k, err)
+		}
+	}
+	if p.Stdin, err = c.expand(p.Stdin); err != nil {
+		errs.add(pos, "%s: %s.stdin: %v", prefix, key, err)
+	}
+}
+
+// expand interpolates a single string.
+func (c *Config) expand(s string) (string, error) {
+	return c.expandRefs(s, false)
+}
+
+// expandCommand interpolates a command string that a shell will run. A
+// ${NAME} whose environment variable is not set is left to the shell, which
+// may define it itself, e.g. as a loop variable. An unset ${secret:NAME}
+// is still an error.
+func (c *Config) expandCommand(s string) (string, error) {
+	return c.expandRefs(s, true)
+}
+
+// expandRefs interpolates s. With keepUnset, references to unset
+// environment variables stay as they are instead of being an error.
+func (c *Config) expandRefs(s string, keepUnset bool) (string, error) {
+	var firstErr error
+	out := placeholder.ReplaceAllStringFunc(s, func(m string) string {
+		if m == "$${" {
+			return "${"
+		}
+		ref := m[2 : len(m)-1]
+
+		if path, ok := strings.CutPrefix(ref, "file:"); ok {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				if firstErr == nil {
+					firstErr = fmt.Errorf("reading secret file: %w", err)
+				}
+				return m
+			}
+			value := strings.TrimSuffix(string(data), "\n")
+			c.addSecret(value)
+			return value
+		}
+
+		name, secret := strings.CutPrefix(ref, "secret:")
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			if (secret || !keepUnset) && firstErr == nil {
+				firstErr = fmt.Errorf("environment variable %s is not set", name)
+			}
+			return m
+		}
+		if secret {
+			c.addSecret(value)
+		}
+		return value
+	})
+	return out, firstErr
+}
+
+func (c *Config) addSecret(value string) {
+	if len(value) < minSecretLen {
+		return
+	}
+	for _, s := range c.secrets {
+		if s == value {
+			return
+		}
+	}
+	c.secrets = append(c.secrets, value)
+	// Longest first, so a secret containing another is replaced whole.
+	sort.Slice(c.secrets, func(i, j int) bool { return len(c.secrets[i]) > len(c.secrets[j]) })
+}
+
+// Redact replaces the value of every secret and file reference in s with
+// "***". Use it for anything derived from checks that ends up in logs, the
+// HTML or recordings.
+func (c *Config) Redact(s string) string {
+	for _, secret := range c.secrets {
+		s = strings.ReplaceAll(s, secret, "***")
+	}
+	return s
+}
@@ -0,0 +1,178 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func interpolateYAML(check string) []byte {
+	return []byte(`
+title: "Test"
+defaults:
+  rules:
+    - match: {}
+      status: { id: ok, label: "✅" }
+groups:
+  - name: "G"
+    tiles:
+      - name: "T"
+        slots:
+          - name: "s"
+            check:
+` + check)
+}
+
+func TestParse_InterpolatesEnvironment(t *testing.T) {
+	t.Setenv("ILIAS_TEST_TOKEN", "s3cr3t-token")
+	t.Setenv("ILIAS_TEST_HOST", "example.com")
+
+	cfg, err := Parse(interpolateYAML(`              target: "https://${ILIAS_TEST_HOST}/health?token=${secret:ILIAS_TEST_TOKEN}"
+              headers:
+                Authorization: "Bearer ${secret:ILIAS_TEST_TOKEN}"
+              body: '{"literal": "$${ILIAS_TEST_TOKEN}", "shell": "${x:-y}"}'
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	check := cfg.Groups[0].Tiles[0].Slots[0].Check
+	if want := "https://example.com/health?token=s3cr3t-token"; check.Target != want {
+		t.Errorf("target = %q, want %q", check.Target, want)
+	}
+	if want := "Bearer s3cr3t-token"; check.Headers["Authorization"] != want {
+		t.Errorf("Authorization = %q, want %q", check.Headers["Authorization"], want)
+	}
+	if want := `{"literal": "${ILIAS_TEST_TOKEN}", "shell": "${x:-y}"}`; check.Body != want {
+		t.Errorf("body = %q, want %q", check.Body, want)
+	}
+
+	// Only secret references are redacted, not every variable.
+	if got, want := cfg.Redact("GET "+check.Target), "GET https://example.com/health?token=***"; got != want {
+		t.Errorf("Redact = %q, want %q", got, want)
+	}
+
+	os.Unsetenv("ILIAS_TEST_UNSET")
+	_, err = Parse(interpolateYAML(`              target: "echo ${secret:ILIAS_TEST_UNSET}"
+`))
+	if err == nil || !strings.Contains(err.Error(), "ILIAS_TEST_UNSET is not set") {
+		t.Errorf("err = %v, want unset secrets to be an error, even in commands", err)
+	}
+}
+
+func TestParse_InterpolationMissingVariable(t *testing.T) {
+	os.Unsetenv("ILIAS_TEST_UNSET")
+	_, err := Parse(interpolateYAML(`              target: "https://localhost/?key=${ILIAS_TEST_UNSET}"
+`))
+	if err == nil {
+		t.Fatal("expected error for unset variable")
+	}
+	for _, want := range []string{`slot "s"`, "check.target", "ILIAS_TEST_UNSET is not set"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("err = %v, want to contain %q", err, want)
+		}
+	}
+}
+
+func TestParse_InterpolationCommandLeavesUnsetToShell(t *testing.T) {
+	os.Unsetenv("ILIAS_TEST_UNSET")
+	t.Setenv("ILIAS_TEST_HOST", "example.com")
+	cfg, err := Parse(interpolateYAML(`              target: 'for f in a; do echo ${f} ${ILIAS_TEST_UNSET} ${ILIAS_TEST_HOST} $${ILIAS_TEST_HOST}; done'
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "for f in a; do echo ${f} ${ILIAS_TEST_UNSET} example.com ${ILIAS_TEST_HOST}; done"
+	if got := cfg.Groups[0].Tiles[0].Slots[0].Check.Target; got != want {
+		t.Errorf("target = %q, want %q", got, want)
+	}
+
+	_, err = Parse(interpolateYAML(`              target: "cat ${file:/nonexistent/ilias-secret}"
+`))
+	if err == nil || !strings.Contains(err.Error(), "reading secret file") {
+		t.Errorf("err = %v, want missing secret files to be an error in commands, too", err)
+	}
+}
+
+func TestParse_InterpolatesGenerate(t *testing.T) {
+	t.Setenv("ILIAS_TEST_TOKEN", "s3cr3t-token")
+	os.Unsetenv("ILIAS_TEST_UNSET")
+	parse := func(generate string) (*Config, error) {
+		return Parse([]byte(`
+title: "Test"
+defaults:
+  rules: [{ match: {}, status: { id: ok, label: "✅" } }]
+groups:
+  - name: "G"
+    tiles:
+      - name: "T"
+        generate: ` + generate + `
+        slots: [{ name: "s", check: "true" }]
+`))
+	}
+
+	cfg, err := parse(`{ command: 'echo ${TOKEN}', env: { TOKEN: "${secret:ILIAS_TEST_TOKEN}" }, stdin: "$${ILIAS_TEST_TOKEN} ${ILIAS_TEST_TOKEN}" }`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	gen := cfg.Groups[0].Tiles[0].Generate
+	if gen.Command != "echo ${TOKEN}" || gen.Env["TOKEN"] != "s3cr3t-token" || gen.Stdin != "${ILIAS_TEST_TOKEN} s3cr3t-token" {
+		t.Errorf("generate = %+v, want env and stdin interpolated, but not the command", gen)
+	}
+	if got := cfg.Redact("token s3cr3t-token"); got != "token ***" {
+		t.Errorf("Redact = %q", got)
+	}
+
+	_, err = parse(`{ command: make, env: { KEY: "${ILIAS_TEST_UNSET}" } }`)
+	if err == nil || !strings.Contains(err.Error(), `tile "T": generate.env "KEY": environment variable ILIAS_TEST_UNSET is not set`) {
+		t.Errorf("err = %v, want the unset variable in generate.env", err)
+	}
+}
+
+func TestParse_InterpolatesSecretFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(path, []byte("from-a-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Parse(interpolateYAML(`              target: "https://example.com"
+              headers:
+                X-Token: "${file:` + path + `}"
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := cfg.Groups[0].Tiles[0].Slots[0].Check.Headers["X-Token"]; got != "from-a-file" {
+		t.Errorf("X-Token = %q, want %q", got, "from-a-file")
+	}
+	if got := cfg.Redact("token from-a-file rejected"); got != "token *** rejected" {
+		t.Errorf("Redact = %q", got)
+	}
+
+	_, err = Parse(interpolateYAML(`              target: "https://example.com"
+              body: "${file:` + path + `.missing}"
+`))
+	if err == nil || !strings.Contains(err.Error(), "check.body") {
+		t.Errorf("err = %v, want check.body error", err)
+	}
+}
+
+func TestRedact_ShortValuesAndNesting(t *testing.T) {
+	var c Config
+	c.addSecret("80")
+	c.addSecret("abcd")
+	c.addSecret("abcdefgh")
+	if got := c.Redact("port 80 abcdefgh abcd"); got != "port 80 *** ***" {
+		t.Errorf("Redact = %q", got)
+	}
+}
+
+func TestParse_HTTPOptionsOnCommandCheck(t *testing.T) {
+	_, err := Parse(interpolateYAML(`              target: "true"
+              method: POST
+`))
+	if err == nil || !strings.Contains(err.Error(), "only supported for http checks") {
+		t.Errorf("err = %v, want http-only error", err)
+	}
+}
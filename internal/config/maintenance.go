@@ -0,0 +1,226 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Maintenance is a planned window during which the slots of a tile or group
+// show the maintenance status and are left out of notifications and exit
+// codes. A window is either recurring, Schedule plus Duration, or a single
+// From–Until range.
+type Maintenance struct {
+	Schedule string    `yaml:"schedule,omitempty"` // cron expression for the start, in local time
+	Duration Duration  `yaml:"duration,omitempty"`
+	From     Timestamp `yaml:"from,omitempty"`
+	Until    Timestamp `yaml:"until,omitempty"`
+	Reason   string    `yaml:"reason,omitempty"`
+
+	Pos Pos `yaml:"-"`
+
+	// cron is the parsed Schedule, set by validate.
+	cron *cron
+}
+
+// Active reports whether t falls into the window.
+func (m *Maintenance) Active(t time.Time) bool {
+	if m.cron == nil {
+		return !t.Before(m.From.Time) && t.Before(m.Until.Time)
+	}
+	// The window is active if it started within the last Duration.
+	t = t.Local()
+	for start := t.Truncate(time.Minute); t.Sub(start) < m.Duration.Duration; start = start.Add(-time.Minute) {
+		if m.cron.matches(start) {
+			return true
+		}
+	}
+	return false
+}
+
+// String describes the window, e.g. "0 22 * * sun for 2h0m0s (backups)".
+func (m *Maintenance) String() string {
+	var s string
+	if m.Schedule != "" {
+		s = m.Schedule + " for " + m.Duration.String()
+	} else {
+		const layout = "2006-01-02 15:04"
+		s = m.From.Local().Format(layout) + " until " + m.Until.Local().Format(layout)
+	}
+	if m.Reason != "" {
+		s += " (" + m.Reason + ")"
+	}
+	return s
+}
+
+// minMaintenance and maxMaintenance bound the duration of recurring
+// windows, which Active searches minute by minute.
+const (
+	minMaintenance = time.Minute
+	maxMaintenance = 7 * 24 * time.Hour
+)
+
+// validateMaintenance checks the windows of a group or tile and parses
+// their schedules.
+func validateMaintenance(errs *Errors, windows []Maintenance, prefix string) {
+	for i := range windows {
+		m := &windows[i]
+		p := fmt.Sprintf("%s, maintenance[%d]", prefix, i)
+		switch {
+		case m.Schedule != "":
+			if !m.From.IsZero() || !m.Until.IsZero() {
+				errs.add(m.Pos, "%s: schedule cannot be combined with from/until", p)
+			}
+			c, err := parseCron(m.Schedule)
+			if err != nil {
+				errs.add(m.Pos, "%s: schedule: %v", p, err)
+			} else {
+				m.cron = c
+			}
+			if m.Duration.Duration < minMaintenance || m.Duration.Duration > maxMaintenance {
+				errs.add(m.Pos, "%s: duration must be between %s and %s with a schedule", p, minMaintenance, maxMaintenance)
+			}
+		case !m.From.IsZero() || !m.Until.IsZero():
+			if m.From.IsZero() || m.Until.IsZero() {
+				errs.add(m.Pos, "%s: from and until are both required", p)
+			} else if !m.Until.After(m.From.Time) {
+				errs.add(m.Pos, "%s: until must be after from", p)
+			}
+			if m.Duration.Duration != 0 {
+				errs.add(m.Pos, "%s: duration is only used with a schedule", p)
+			}
+		default:
+			errs.add(m.Pos, "%s: either schedule and duration or from and until are required", p)
+		}
+	}
+}
+
+// Timestamp wraps time.Time for YAML. Besides RFC 3339 it accepts
+// "2006-01-02 15:04" and "2006-01-02", both in local time.
+type Timestamp struct {
+	time.Time
+}
+
+// timestampLayouts are tried in order by Timestamp.UnmarshalYAML.
+var timestampLayouts = []string{time.RFC3339, "2006-01-02 15:04", "2006-01-02T15:04", "2006-01-02"}
+
+// UnmarshalYAML parses a timestamp string.
+func (t *Timestamp) UnmarshalYAML(value *yaml.Node) error {
+	for _, layout := range timestampLayouts {
+		if parsed, err := time.ParseInLocation(layout, value.Value, time.Local); err == nil {
+			t.Time = parsed
+			return nil
+		}
+	}
+	return nodeErrorf(value, "invalid time %q (want e.g. \"2024-05-01 22:00\")", value.Value)
+}
+
+// cron is a parsed five-field cron expression: minute, hour, day of month,
+// month and day of week.
+type cron struct {
+	minute, hour, dom, month, dow uint64 // bit i set: value i matches
+	domStar, dowStar              bool
+}
+
+var cronFields = []struct {
+	name     string
+	min, max int
+	names    []string // for values from min on, e.g. "jan"
+}{
+	{"minute", 0, 59, nil},
+	{"hour", 0, 23, nil},
+	{"day of month", 1, 31, nil},
+	{"month", 1, 12, []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}},
+	{"day of week", 0, 7, []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}}, // 0 and 7 are Sunday
+}
+
+// parseCron parses fields of numbers, ranges ("1-5"), lists ("1,15"),
+// steps ("*/15", "0-30/10") and "*". Months and days of week may also be
+// given by their English three-letter names ("mon-fri").
+func parseCron(expr string) (*cron, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("want 5 fields (minute hour day-of-month month day-of-week), got %d", len(fields))
+	}
+	var sets [5]uint64
+	for i, f := range fields {
+		set, err := parseCronField(strings.ToLower(f), cronFields[i].min, cronFields[i].max, cronFields[i].names)
+		if err != nil {
+			return nil, fmt.Errorf("%s %q: %v", cronFields[i].name, f, err)
+		}
+		sets[i] = set
+	}
+	if sets[4]&(1<<7) != 0 {
+		sets[4] |= 1
+	}
+	return &cron{
+		minute: sets[0], hour: sets[1], dom: sets[2], month: sets[3], dow: sets[4],
+		domStar: fields[2] == "*", dowStar: fields[4] == "*",
+	}, nil
+}
+
+func parseCronField(f string, min, max int, names []string) (uint64, error) {
+	value := func(s string) (int, error) {
+		if i := slices.Index(names, s); i >= 0 {
+			return min + i, nil
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return 0, fmt.Errorf("invalid value %q", s)
+		}
+		return n, nil
+	}
+	var set uint64
+	for _, part := range strings.Split(f, ",") {
+		rng, stepStr, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepStr)
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("invalid step %q", stepStr)
+			}
+			step = n
+		}
+		lo, hi := min, max
+		if rng != "*" {
+			loStr, hiStr, isRange := strings.Cut(rng, "-")
+			var err error
+			if lo, err = value(loStr); err != nil {
+				return 0, err
+			}
+			hi = lo
+			if isRange {
+				if hi, err = value(hiStr); err != nil {
+					return 0, err
+				}
+			} else if hasStep {
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("out of range %d-%d", min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			set |= 1 << v
+		}
+	}
+	return set, nil
+}
+
+// matches reports whether the minute t starts matches. As in cron, when
+// both day of month and day of week are restricted, either may match.
+func (c *cron) matches(t time.Time) bool {
+	if c.minute&(1<<t.Minute()) == 0 || c.hour&(1<<t.Hour()) == 0 || c.month&(1<<int(t.Month())) == 0 {
+		return false
+	}
+	dom := c.dom&(1<<t.Day()) != 0
+	dow := c.dow&(1<<int(t.Weekday())) != 0
+	if c.domStar || c.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
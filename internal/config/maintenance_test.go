@@ -0,0 +1,139 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCron(t *testing.T) {
+	// Wednesday 2024-05-01.
+	at := func(day, hour, min int) time.Time { return time.Date(2024, 5, day, hour, min, 0, 0, time.Local) }
+	tests := []struct {
+		expr string
+		t    time.Time
+		want bool
+	}{
+		{"* * * * *", at(1, 12, 34), true},
+		{"0 22 * * *", at(1, 22, 0), true},
+		{"0 22 * * *", at(1, 22, 1), false},
+		{"*/15 * * * *", at(1, 3, 45), true},
+		{"*/15 * * * *", at(1, 3, 46), false},
+		{"0-30/10 * * * *", at(1, 3, 20), true},
+		{"0-30/10 * * * *", at(1, 3, 40), false},
+		{"0 9 * * mon-fri", at(1, 9, 0), true},
+		{"0 9 * * MON-FRI", at(4, 9, 0), false}, // Saturday
+		{"0 3 * * 7", at(5, 3, 0), true},        // 7 is Sunday
+		{"0 0 1,15 * *", at(15, 0, 0), true},
+		{"0 0 * may *", at(20, 0, 0), true},
+		// Day of month and day of week are ORed when both are restricted.
+		{"0 0 13 * fri", at(3, 0, 0), true},
+		{"0 0 13 * fri", at(13, 0, 0), true},
+		{"0 0 13 * fri", at(14, 0, 0), false},
+	}
+	for _, tt := range tests {
+		c, err := parseCron(tt.expr)
+		if err != nil {
+			t.Errorf("parseCron(%q): %v", tt.expr, err)
+			continue
+		}
+		if got := c.matches(tt.t); got != tt.want {
+			t.Errorf("%q matches %s = %v, want %v", tt.expr, tt.t.Format("Mon 2006-01-02 15:04"), got, tt.want)
+		}
+	}
+
+	for _, expr := range []string{"* * * *", "60 * * * *", "* * * * mo", "*/0 * * * *", "5-1 * * * *"} {
+		if _, err := parseCron(expr); err == nil {
+			t.Errorf("parseCron(%q): expected error", expr)
+		}
+	}
+}
+
+func TestMaintenance_Active(t *testing.T) {
+	cfg, err := ParseStrict([]byte(`title: T
+defaults:
+  rules: [{ match: {}, status: { id: ok, label: "✅" } }]
+groups:
+  - name: G
+    maintenance:
+      - { schedule: "0 23 * * *", duration: 2h }
+      - { from: "2024-05-01 10:00", until: 2024-05-03T00:00:00Z }
+    tiles: [{ name: T, slots: [{ name: s, check: "true" }] }]
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	nightly, once := &cfg.Groups[0].Maintenance[0], &cfg.Groups[0].Maintenance[1]
+
+	for _, tt := range []struct {
+		t    time.Time
+		want bool
+	}{
+		{time.Date(2024, 5, 1, 22, 59, 0, 0, time.Local), false},
+		{time.Date(2024, 5, 1, 23, 0, 0, 0, time.Local), true},
+		{time.Date(2024, 5, 2, 0, 59, 59, 0, time.Local), true},
+		{time.Date(2024, 5, 2, 1, 0, 0, 0, time.Local), false},
+	} {
+		if got := nightly.Active(tt.t); got != tt.want {
+			t.Errorf("nightly active at %s = %v, want %v", tt.t.Format(time.TimeOnly), got, tt.want)
+		}
+	}
+
+	if want := time.Date(2024, 5, 1, 10, 0, 0, 0, time.Local); !once.From.Equal(want) {
+		t.Errorf("from = %v, want %v", once.From, want)
+	}
+	if until := time.Date(2024, 5, 3, 0, 0, 0, 0, time.UTC); !once.Active(until.Add(-time.Second)) || once.Active(until) {
+		t.Errorf("one-off window should end at %v", until)
+	}
+}
+
+func TestParse_MaintenanceDurationBounds(t *testing.T) {
+	for _, d := range []string{"1m", "168h"} {
+		_, err := Parse([]byte(`title: T
+groups:
+  - name: G
+    tiles:
+      - name: T
+        maintenance: [{ schedule: "0 2 * * *", duration: ` + d + ` }]
+        slots: [{ name: s, check: "true", rules: [{ match: {}, status: { id: ok, label: "✅" } }] }]
+`))
+		if err != nil {
+			t.Errorf("duration %s: %v", d, err)
+		}
+	}
+}
+
+func TestParse_MaintenanceErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		window  string
+		wantErr string
+	}{
+		{"bad schedule", `{ schedule: "0 25 * * *", duration: 1h }`, `schedule: hour "25": out of range 0-23`},
+		{"no duration", `{ schedule: "0 2 * * *" }`, "duration must be between 1m0s and 168h0m0s"},
+		{"too short", `{ schedule: "0 2 * * *", duration: 59s }`, "duration must be between 1m0s and 168h0m0s"},
+		{"too long", `{ schedule: "0 2 * * *", duration: 169h }`, "duration must be between 1m0s and 168h0m0s"},
+		{"schedule and range", `{ schedule: "0 2 * * *", duration: 1h, from: 2024-05-01 }`, "schedule cannot be combined with from/until"},
+		{"until only", `{ until: 2024-05-01 }`, "from and until are both required"},
+		{"reversed", `{ from: 2024-05-02, until: 2024-05-01 }`, "until must be after from"},
+		{"empty", `{ reason: x }`, "either schedule and duration or from and until are required"},
+		{"bad time", `{ from: tomorrow, until: 2024-05-01 }`, `invalid time "tomorrow"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(`groups:
+  - name: G
+    tiles:
+      - name: T
+        maintenance: [` + tt.window + `]
+        slots: [{ name: s, check: "true" }]
+`))
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %q, want to contain %q", err.Error(), tt.wantErr)
+			}
+		})
+	}
+}
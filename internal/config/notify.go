@@ -0,0 +1,134 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net"
+	"slices"
+	"strings"
+	"text/template"
+)
+
+// Notify configures notifications about slots whose status changed since the
+// previous run.
+type Notify struct {
+	Webhooks []Webhook `yaml:"webhooks,omitempty"`
+	Email    *Email    `yaml:"email,omitempty"`
+}
+
+// Webhook is an HTTP request sent for every status change.
+//
+// URL, Headers and Body may reference ${ENV_VAR} and ${file:/path} like
+// checks. Body is a text/template executed with the change (see
+// notify.Change); it defaults to a JSON document with all of its fields.
+type Webhook struct {
+	URL     string            `yaml:"url"`
+	Method  string            `yaml:"method,omitempty"` // default POST
+	Headers map[string]string `yaml:"headers,omitempty"`
+	Body    string            `yaml:"body,omitempty"`
+	Timeout Duration          `yaml:"timeout,omitempty"` // default 10s
+
+	Pos Pos `yaml:"-"`
+}
+
+// Email sends all changes of a run in one message per recipient, through an
+// SMTP server; recipients of the same changes share a message. Changes in a
+// group listed under Groups go to those recipients instead of To.
+//
+// Username and Password may reference ${ENV_VAR}, ${secret:ENV_VAR} and
+// ${file:/path}.
+type Email struct {
+	Server   string                `yaml:"server"` // host:port
+	StartTLS *bool                 `yaml:"starttls,omitempty"`
+	Username string                `yaml:"username,omitempty"`
+	Password string                `yaml:"password,omitempty"`
+	From     string                `yaml:"from"`
+	To       StringList            `yaml:"to,omitempty"`
+	Groups   map[string]StringList `yaml:"groups,omitempty"`
+	Timeout  Duration              `yaml:"timeout,omitempty"` // default 30s
+
+	Pos Pos `yaml:"-"`
+}
+
+// Recipients returns who is told about changes in a group.
+func (e *Email) Recipients(group string) []string {
+	if to, ok := e.Groups[group]; ok {
+		return to
+	}
+	return e.To
+}
+
+// BodyTemplate parses the webhook body. Besides the text/template builtins it
+// has "json", which encodes a value as JSON, e.g. {"text": {{json .Output}}}.
+func (w *Webhook) BodyTemplate() (*template.Template, error) {
+	return template.New("body").Funcs(template.FuncMap{"json": toJSON}).Parse(w.Body)
+}
+
+func toJSON(v any) (string, error) {
+	data, err := json.Marshal(v)
+	return string(data), err
+}
+
+// validateNotify checks the notify section. It runs after interpolation, so
+// a url from the environment is checked too; values are redacted.
+func (c *Config) validateNotify(errs *Errors) {
+	if c.Notify == nil {
+		return
+	}
+	for i := range c.Notify.Webhooks {
+		w := &c.Notify.Webhooks[i]
+		pos := w.Pos
+		if pos.IsZero() {
+			pos = c.at("notify")
+		}
+		prefix := fmt.Sprintf("notify.webhooks[%d]", i)
+		if !strings.HasPrefix(w.URL, "http://") && !strings.HasPrefix(w.URL, "https://") {
+			errs.add(pos, "%s: url must start with http:// or https://, got %q", prefix, c.Redact(w.URL))
+		}
+		if w.Body != "" {
+			if _, err := w.BodyTemplate(); err != nil {
+				errs.add(pos, "%s: body: %v", prefix, err)
+			}
+		}
+	}
+
+	if e := c.Notify.Email; e != nil {
+		pos := e.Pos
+		if _, _, err := net.SplitHostPort(e.Server); err != nil {
+			errs.add(pos, "notify.email: server must be host:port, got %q", e.Server)
+		}
+		if e.From == "" {
+			errs.add(pos, "notify.email: from is required")
+		}
+		if len(e.To) == 0 && len(e.Groups) == 0 {
+			errs.add(pos, "notify.email: to or groups is required")
+		}
+		for _, name := range slices.Sorted(maps.Keys(e.Groups)) {
+			if c.group(name) == nil {
+				errs.add(pos, "notify.email.groups: there is no group %q", name)
+			}
+		}
+		if e.Password != "" && e.Username == "" {
+			errs.add(pos, "notify.email: password is set but username is not")
+		}
+	}
+}
+
+// Notifies reports whether anything should be told about status changes:
+// there is a notify section or an on_change command.
+func (c *Config) Notifies() bool {
+	if c.Notify != nil || c.OnChange != "" {
+		return true
+	}
+	for _, g := range c.Groups {
+		for _, t := range g.Tiles {
+			for _, s := range t.Slots {
+				if s.OnChange != "" {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+// notifyYAML is a minimal valid config to append a notify section to.
+const notifyYAML = `title: T
+groups:
+  - name: G
+    tiles:
+      - name: X
+        slots:
+          - { name: s, check: "true", rules: [{ match: {}, status: { id: ok, label: "✅" } }] }
+`
+
+func TestParse_Notify(t *testing.T) {
+	t.Setenv("HOOK_TOKEN", "s3cret-token")
+	cfg, err := ParseStrict([]byte(notifyYAML + `notify:
+  webhooks:
+    - url: https://chat.example.com/hook
+      headers: { Authorization: "Bearer ${secret:HOOK_TOKEN}" }
+      body: '{"text": {{json .Slot}}}'
+      timeout: 5s
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	w := cfg.Notify.Webhooks[0]
+	if w.Headers["Authorization"] != "Bearer s3cret-token" || w.Timeout.Seconds() != 5 {
+		t.Errorf("webhook = %+v", w)
+	}
+	if got := cfg.Redact(w.Headers["Authorization"]); got != "Bearer ***" {
+		t.Errorf("Redact = %q, want the token redacted", got)
+	}
+}
+
+func TestParse_NotifyErrors(t *testing.T) {
+	_, err := Parse([]byte(notifyYAML + `notify:
+  webhooks:
+    - url: chat.example.com/hook
+    - url: https://chat.example.com/hook
+      body: "{{ .Slot"
+`))
+	if err == nil {
+		t.Fatal("expected errors")
+	}
+	for _, want := range []string{
+		`notify.webhooks[0]: url must start with http:// or https://, got "chat.example.com/hook"`,
+		`notify.webhooks[1]: body: template: body:1: unclosed action`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error = %q, want to contain %q", err.Error(), want)
+		}
+	}
+}
+
+func TestParse_NotifyEmail(t *testing.T) {
+	t.Setenv("SMTP_PASSWORD", "hunter22")
+	cfg, err := ParseStrict([]byte(notifyYAML + `notify:
+  email:
+    server: mail.example.com:587
+    starttls: true
+    username: ilias
+    password: ${SMTP_PASSWORD}
+    from: ilias@example.com
+    to: ops@example.com
+    groups:
+      G: [g-team@example.com]
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	e := cfg.Notify.Email
+	if e.Password != "hunter22" || !*e.StartTLS {
+		t.Errorf("email = %+v", e)
+	}
+	if got := e.Recipients("G"); len(got) != 1 || got[0] != "g-team@example.com" {
+		t.Errorf("recipients for G = %v", got)
+	}
+	if got := e.Recipients("Other"); len(got) != 1 || got[0] != "ops@example.com" {
+		t.Errorf("recipients for Other = %v, want to", got)
+	}
+
+	_, err = Parse([]byte(notifyYAML + `notify:
+  email:
+    server: mail.example.com
+    password: x
+    groups: { Nope: [a@example.com] }
+`))
+	for _, want := range []string{
+		`line 10, column 5: notify.email: server must be host:port, got "mail.example.com"`,
+		"notify.email: from is required",
+		`notify.email.groups: there is no group "Nope"`,
+		"notify.email: password is set but username is not",
+	} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("error = %v, want to contain %q", err, want)
+		}
+	}
+}
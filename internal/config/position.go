@@ -0,0 +1,295 @@
+package config
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Pos is a position in a config file. Line and Column are 1-based; the zero
+// Pos means the position is unknown.
+type Pos struct {
+	File   string
+	Line   int
+	Column int
+}
+
+// IsZero reports whether the position is unknown.
+func (p Pos) IsZero() bool {
+	return p.Line == 0
+}
+
+// String formats the position as "file:line:col", the form editors and CI
+// annotations understand. Without a file it is "line L, column C". The
+// column is left out when unknown.
+func (p Pos) String() string {
+	switch {
+	case p.IsZero():
+		return p.File
+	case p.File == "" && p.Column == 0:
+		return fmt.Sprintf("line %d", p.Line)
+	case p.File == "":
+		return fmt.Sprintf("line %d, column %d", p.Line, p.Column)
+	case p.Column == 0:
+		return fmt.Sprintf("%s:%d", p.File, p.Line)
+	default:
+		return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Column)
+	}
+}
+
+// nodePos returns the position of a YAML node.
+func nodePos(n *yaml.Node) Pos {
+	return Pos{Line: n.Line, Column: n.Column}
+}
+
+// Error is a problem with the configuration at a known position.
+type Error struct {
+	Pos Pos
+	Msg string
+}
+
+// Error formats the error as "config: file:line:col: message".
+func (e *Error) Error() string {
+	if e.Pos.IsZero() && e.Pos.File == "" {
+		return "config: " + e.Msg
+	}
+	return "config: " + e.Pos.String() + ": " + e.Msg
+}
+
+// Errors is every problem found in a configuration. Load and Parse return it
+// so that all problems can be fixed in one go; use errors.As to get at the
+// individual positions.
+type Errors []*Error
+
+// Error lists the problems, one per line.
+func (e Errors) Error() string {
+	lines := make([]string, len(e))
+	for i, err := range e {
+		lines[i] = err.Error()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Unwrap returns the individual errors.
+func (e Errors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+// sort orders the problems by file and position. Problems without a
+// position come first.
+func (e Errors) sort() {
+	slices.SortStableFunc(e, func(a, b *Error) int {
+		return cmp.Or(
+			cmp.Compare(a.Pos.File, b.Pos.File),
+			cmp.Compare(a.Pos.Line, b.Pos.Line),
+			cmp.Compare(a.Pos.Column, b.Pos.Column),
+		)
+	})
+}
+
+// add appends an *Error at pos.
+func (e *Errors) add(pos Pos, format string, args ...any) {
+	*e = append(*e, &Error{Pos: pos, Msg: fmt.Sprintf(format, args...)})
+}
+
+// nodeErrorf reports a problem with a YAML node from a custom unmarshaler.
+// It is a *yaml.TypeError so that yaml.v3 keeps decoding the rest of the
+// document; decode turns it back into an *Error with typeErrors.
+func nodeErrorf(n *yaml.Node, format string, args ...any) error {
+	msg := fmt.Sprintf(format, args...)
+	return &yaml.TypeError{Errors: []string{fmt.Sprintf("line %d, column %d: %s", n.Line, n.Column, msg)}}
+}
+
+// yamlPos matches the position prefix of yaml.v3 errors ("line N: ") and of
+// nodeErrorf ("line N, column C: ").
+var yamlPos = regexp.MustCompile(`^line (\d+)(?:, column (\d+))?: `)
+
+// typeErrors converts the messages of a yaml.TypeError into Errors.
+func typeErrors(te *yaml.TypeError, file string) Errors {
+	var errs Errors
+	for _, msg := range te.Errors {
+		pos := Pos{File: file}
+		if m := yamlPos.FindStringSubmatch(msg); m != nil {
+			pos.Line, _ = strconv.Atoi(m[1])
+			pos.Column, _ = strconv.Atoi(m[2])
+			msg = msg[len(m[0]):]
+		}
+		errs = append(errs, &Error{Pos: pos, Msg: msg})
+	}
+	return errs
+}
+
+// yamlLine matches the "line N: " part of yaml.v3 syntax errors.
+var yamlLine = regexp.MustCompile(`line (\d+): `)
+
+// decodeError attaches file to a yaml.v3 syntax error, so its "line N: ..."
+// message points into the file.
+func decodeError(err error, file string) error {
+	if file == "" {
+		return fmt.Errorf("parsing config: %w", err)
+	}
+	return fmt.Errorf("parsing config: %s", yamlLine.ReplaceAllString(err.Error(), file+":$1: "))
+}
+
+// keyPositions returns the positions of the values of the top-level keys in
+// a document, plus "themes.<name>" for every theme and "rule_sets.<name>"
+// for every rule set. The key "" is the start of the document, where
+// problems of the config as a whole are reported.
+func keyPositions(doc *yaml.Node) map[string]Pos {
+	pos := map[string]Pos{"": {Line: 1, Column: 1}}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return pos
+	}
+	root := doc.Content[0]
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		pos[key.Value] = nodePos(value)
+		if (key.Value == "themes" || key.Value == "rule_sets") && value.Kind == yaml.MappingNode {
+			for j := 0; j+1 < len(value.Content); j += 2 {
+				pos[key.Value+"."+value.Content[j].Value] = nodePos(value.Content[j+1])
+			}
+		}
+	}
+	return pos
+}
+
+// setPositions records where each group, tile, slot and rule of cfg was
+// declared in doc. This is done on the node tree rather than in UnmarshalYAML
+// methods: an unmarshaler that reports a problem makes yaml.v3 drop the whole
+// sequence element, which would turn one bad regex into a missing slot.
+// A sequence whose length no longer matches (an element failed to decode
+// for other reasons) is skipped.
+func (c *Config) setPositions(doc *yaml.Node, file string) {
+	for k, p := range c.keyPos {
+		p.File = file
+		c.keyPos[k] = p
+	}
+	if len(doc.Content) == 0 {
+		return
+	}
+	root := doc.Content[0]
+	at := func(n *yaml.Node) Pos {
+		p := nodePos(n)
+		p.File = file
+		return p
+	}
+	setRules := func(rules []Rule, n *yaml.Node) {
+		for ri, rn := range items(n, len(rules)) {
+			rules[ri].Pos = at(rn)
+		}
+	}
+
+	if c.Defaults != nil {
+		setRules(c.Defaults.Rules, mappingValue(mappingValue(root, "defaults"), "rules"))
+	}
+	for name, rules := range c.RuleSets {
+		setRules(rules, mappingValue(mappingValue(root, "rule_sets"), name))
+	}
+	if c.Notify != nil {
+		notify := mappingValue(root, "notify")
+		for wi, wn := range items(mappingValue(notify, "webhooks"), len(c.Notify.Webhooks)) {
+			c.Notify.Webhooks[wi].Pos = at(wn)
+		}
+		if en := mappingValue(notify, "email"); en != nil && c.Notify.Email != nil {
+			c.Notify.Email.Pos = at(en)
+		}
+	}
+	for gi, gn := range items(mappingValue(root, "groups"), len(c.Groups)) {
+		g := &c.Groups[gi]
+		g.Pos = at(gn)
+		if g.Discover != nil && !g.Discover.Pos.IsZero() {
+			g.Discover.Pos.File = file
+		}
+		if g.Defaults != nil {
+			setRules(g.Defaults.Rules, mappingValue(mappingValue(gn, "defaults"), "rules"))
+		}
+		for mi, mn := range items(mappingValue(gn, "maintenance"), len(g.Maintenance)) {
+			g.Maintenance[mi].Pos = at(mn)
+		}
+		for ti, tn := range items(mappingValue(gn, "tiles"), len(g.Tiles)) {
+			setTilePositions(&g.Tiles[ti], tn, file)
+		}
+	}
+}
+
+// setTilePositions records where a tile, its slots and their rules were
+// declared; see setPositions.
+func setTilePositions(t *Tile, tn *yaml.Node, file string) {
+	at := func(n *yaml.Node) Pos {
+		p := nodePos(n)
+		p.File = file
+		return p
+	}
+	t.Pos = at(tn)
+	for mi, mn := range items(mappingValue(tn, "maintenance"), len(t.Maintenance)) {
+		t.Maintenance[mi].Pos = at(mn)
+	}
+	if t.Defaults != nil {
+		for ri, rn := range items(mappingValue(mappingValue(tn, "defaults"), "rules"), len(t.Defaults.Rules)) {
+			t.Defaults.Rules[ri].Pos = at(rn)
+		}
+	}
+	for si, sn := range items(mappingValue(tn, "slots"), len(t.Slots)) {
+		s := &t.Slots[si]
+		s.Pos = at(sn)
+		if !s.Check.Pos.IsZero() {
+			s.Check.Pos.File = file
+		}
+		for ri, rn := range items(mappingValue(sn, "rules"), len(s.Rules)) {
+			s.Rules[ri].Pos = at(rn)
+		}
+	}
+}
+
+// resolve follows YAML aliases to the node they refer to.
+func resolve(n *yaml.Node) *yaml.Node {
+	for n != nil && n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	return n
+}
+
+// mappingValue returns the value of key in a mapping node, or nil.
+func mappingValue(n *yaml.Node, key string) *yaml.Node {
+	n = resolve(n)
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return resolve(n.Content[i+1])
+		}
+	}
+	return nil
+}
+
+// items returns the elements of a sequence node, or nil if n is not a
+// sequence of length want.
+func items(n *yaml.Node, want int) []*yaml.Node {
+	if n == nil || n.Kind != yaml.SequenceNode || len(n.Content) != want {
+		return nil
+	}
+	out := make([]*yaml.Node, want)
+	for i, item := range n.Content {
+		out[i] = resolve(item)
+	}
+	return out
+}
+
+// at returns the position of a top-level key, or the start of the document
+// if the key is not set.
+func (c *Config) at(key string) Pos {
+	if pos, ok := c.keyPos[key]; ok {
+		return pos
+	}
+	return c.keyPos[""]
+}
@@ -0,0 +1,204 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParse_ErrorPositions(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantPos Pos
+		wantMsg string
+	}{
+		{
+			name: "bad check type",
+			yaml: `title: T
+groups:
+  - name: G
+    tiles:
+      - name: T
+        slots:
+          - name: s
+            check:
+              type: ftp
+              target: x
+            rules: [{ match: {}, status: { id: ok, label: "✅" } }]
+`,
+			wantPos: Pos{Line: 9, Column: 15},
+			wantMsg: "check.type must be",
+		},
+		{
+			name: "missing label",
+			yaml: `title: T
+groups:
+  - name: G
+    tiles:
+      - name: T
+        slots:
+          - name: s
+            check: "true"
+            rules:
+              - match: {}
+                status: { id: ok }
+`,
+			wantPos: Pos{Line: 10, Column: 17},
+			wantMsg: "rule[0]: status.label is required",
+		},
+		{
+			name:    "unknown theme",
+			yaml:    "title: T\ntheme: nope\ngroups: []\n",
+			wantPos: Pos{Line: 2, Column: 8},
+			wantMsg: "theme must be",
+		},
+		{
+			name:    "missing title",
+			yaml:    "# no title\ngroups: []\n",
+			wantPos: Pos{Line: 1, Column: 1},
+			wantMsg: "title is required",
+		},
+		{
+			name:    "invalid duration",
+			yaml:    "title: T\nrefresh: soon\n",
+			wantPos: Pos{Line: 2, Column: 10},
+			wantMsg: `invalid duration "soon"`,
+		},
+		{
+			name:    "invalid output regex",
+			yaml:    "title: T\ndefaults:\n  rules:\n    - match: { output: \"(\" }\n      status: { id: ok, label: x }\n",
+			wantPos: Pos{Line: 4, Column: 14},
+			wantMsg: "invalid output regex",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.yaml))
+			var errs Errors
+			if !errors.As(err, &errs) {
+				t.Fatalf("err = %v, want Errors", err)
+			}
+			var e *Error
+			for _, candidate := range errs {
+				if strings.Contains(candidate.Msg, tt.wantMsg) {
+					e = candidate
+				}
+			}
+			if e == nil {
+				t.Fatalf("err = %v, want to contain %q", err, tt.wantMsg)
+			}
+			if e.Pos != tt.wantPos {
+				t.Errorf("pos = %+v, want %+v", e.Pos, tt.wantPos)
+			}
+			if !strings.Contains(e.Msg, tt.wantMsg) {
+				t.Errorf("msg = %q, want to contain %q", e.Msg, tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestLoad_ErrorsIncludeFileLineColumn(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("title: T\ngroups:\n  - name: G\n    tiles: []\n")
+	_, err := Load(path)
+	if want := "config: " + path + ":3:5: group[0] \"G\": at least one tile is required"; err == nil || err.Error() != want {
+		t.Errorf("err = %v, want %q", err, want)
+	}
+
+	write("groups:\n  - name: G\n    tiles: [{ name: T, slots: [{ name: s, check: \"true\", rules: [{ match: {}, status: { id: ok, label: x } }] }] }]\n")
+	_, err = Load(path)
+	if want := "config: " + path + ":1:1: title is required"; err == nil || err.Error() != want {
+		t.Errorf("err = %v, want %q", err, want)
+	}
+
+	write("title: T\nrefresh: [1m]\n")
+	_, err = Load(path)
+	if want := path + ":2: cannot unmarshal"; err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("err = %v, want to contain %q", err, want)
+	}
+
+	write("title: T\ngroups: [\n")
+	_, err = Load(path)
+	if err == nil || !strings.Contains(err.Error(), path+":") {
+		t.Errorf("err = %v, want syntax error naming the file", err)
+	}
+}
+
+func TestPos_String(t *testing.T) {
+	tests := []struct {
+		pos  Pos
+		want string
+	}{
+		{Pos{File: "a.yaml", Line: 3, Column: 5}, "a.yaml:3:5"},
+		{Pos{Line: 3, Column: 5}, "line 3, column 5"},
+		{Pos{File: "a.yaml"}, "a.yaml"},
+		{Pos{}, ""},
+	}
+	for _, tt := range tests {
+		if got := tt.pos.String(); got != tt.want {
+			t.Errorf("%+v.String() = %q, want %q", tt.pos, got, tt.want)
+		}
+	}
+}
+
+func TestParse_ReportsAllErrors(t *testing.T) {
+	yaml := `title: T
+refresh: soon
+groups:
+  - name: G
+    tiles:
+      - name: X
+        slots:
+          - name: a
+            check: { type: ftp, target: x }
+          - check: "true"
+            rules:
+              - match: { output: "(" }
+                status: { id: ok }
+      - slots: []
+`
+	_, err := Parse([]byte(yaml))
+	var errs Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("err = %v, want Errors", err)
+	}
+
+	want := []string{
+		`line 2, column 10: invalid duration "soon"`,
+		`line 8, column 13: group[0] "G", tile[0] "X", slot[0] "a": at least one rule is required`,
+		`line 9, column 20: group[0] "G", tile[0] "X", slot[0] "a": check.type must be`,
+		`line 10, column 13: group[0] "G", tile[0] "X", slot[1]: name is required`,
+		`line 12, column 17: group[0] "G", tile[0] "X", slot[1], rule[0]: status.label is required`,
+		`line 12, column 24: invalid output regex "("`,
+		`line 14, column 9: group[0] "G", tile[1]: name is required`,
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("got %d errors, want %d:\n%v", len(errs), len(want), err)
+	}
+	for i, w := range want {
+		if !strings.Contains(errs[i].Error(), w) {
+			t.Errorf("errs[%d] = %q, want to contain %q", i, errs[i], w)
+		}
+	}
+
+	// The combined error keeps working with strings.Contains and errors.As.
+	if !strings.Contains(err.Error(), "status.label is required") {
+		t.Errorf("combined error = %q", err)
+	}
+	var first *Error
+	if !errors.As(err, &first) || first.Pos.Line != 2 {
+		t.Errorf("errors.As(*Error) = %v, want the first problem", first)
+	}
+}
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// useRuleSets fills in the rules of the global and group defaults that
+// name a rule set, and of the tiles of every group; see useTileRuleSets.
+func (c *Config) useRuleSets(errs *Errors) {
+	if d := c.Defaults; d != nil {
+		c.useRuleSet(errs, c.at("defaults"), "defaults", d.RuleSet, &d.Rules)
+	}
+	for gi := range c.Groups {
+		g := &c.Groups[gi]
+		if d := g.Defaults; d != nil {
+			c.useRuleSet(errs, g.Pos, fmt.Sprintf("group[%d] %q, defaults", gi, g.Name), d.RuleSet, &d.Rules)
+		}
+		for ti := range g.Tiles {
+			c.useTileRuleSets(errs, gi, g.Name, ti, &g.Tiles[ti])
+		}
+	}
+}
+
+// useTileRuleSets fills in the rules of the defaults and slots of tile t
+// that name a rule set. It runs before applyDefaults, so that slots inherit
+// the rules of a rule set like any others.
+func (c *Config) useTileRuleSets(errs *Errors, gi int, gname string, ti int, t *Tile) {
+	prefix := fmt.Sprintf("group[%d] %q, tile[%d]", gi, gname, ti)
+	if t.Name != "" {
+		prefix = fmt.Sprintf("group[%d] %q, tile[%d] %q", gi, gname, ti, t.Name)
+	}
+	if d := t.Defaults; d != nil {
+		c.useRuleSet(errs, t.Pos, prefix+", defaults", d.RuleSet, &d.Rules)
+	}
+	for si := range t.Slots {
+		s := &t.Slots[si]
+		slotPrefix := fmt.Sprintf("%s, slot[%d]", prefix, si)
+		if s.Name != "" {
+			slotPrefix = fmt.Sprintf("%s, slot[%d] %q", prefix, si, s.Name)
+		}
+		if c.useRuleSet(errs, s.Pos, slotPrefix, s.RuleSet, &s.Rules) {
+			s.RulesFrom = fmt.Sprintf("rule set %q", s.RuleSet)
+		}
+	}
+}
+
+// useRuleSet sets rules to the rule set called name, if there is one, and
+// reports whether it did.
+func (c *Config) useRuleSet(errs *Errors, pos Pos, prefix, name string, rules *[]Rule) bool {
+	if name == "" {
+		return false
+	}
+	if len(*rules) > 0 {
+		errs.add(pos, "%s: rules and rule_set cannot both be set", prefix)
+		return false
+	}
+	set, ok := c.RuleSets[name]
+	if !ok {
+		errs.add(pos, "%s: rule_set: no rule set %q under rule_sets", prefix, name)
+		return false
+	}
+	*rules = set
+	return true
+}
+
+// validateRuleSets checks the rules of every rule set, once, rather than in
+// every slot that uses them.
+func (c *Config) validateRuleSets(errs *Errors) {
+	for _, name := range slices.Sorted(maps.Keys(c.RuleSets)) {
+		prefix := fmt.Sprintf("rule_sets %q", name)
+		if len(c.RuleSets[name]) == 0 {
+			errs.add(c.at("rule_sets."+name), "%s: at least one rule is required", prefix)
+		}
+		for ri, r := range c.RuleSets[name] {
+			validateStatus(errs, r, fmt.Sprintf("%s, rule[%d]", prefix, ri))
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package config
+
+import (
+	"maps"
+	"reflect"
+)
+
+// Schema returns a JSON Schema (draft-07) for the config file format, for
+// use with editors such as yaml-language-server. It is derived from the
+// Config types, so it follows the same keys as strict mode; the string and
+// map forms of custom unmarshalers are described by hand.
+func Schema() map[string]any {
+	s := &schemaBuilder{defs: make(map[string]any)}
+	root := s.object(reflect.TypeOf(Config{}))
+	s.addTemplates(root)
+	root["$schema"] = "http://json-schema.org/draft-07/schema#"
+	root["title"] = "ilias configuration"
+	root["definitions"] = s.defs
+	return root
+}
+
+// durationPattern matches the durations ilias configs use, e.g. "1m30s".
+// time.ParseDuration accepts a few more forms (signs, ".5s").
+const durationPattern = `^([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$`
+
+// byteSizePattern matches the units ByteSize accepts.
+const byteSizePattern = `^[0-9]+ *([KMGTkmgt]([iI]?[bB])?|[bB])?$`
+
+// timestampPattern matches the forms Timestamp accepts.
+const timestampPattern = `^[0-9]{4}-[0-9]{2}-[0-9]{2}([T ][0-9]{2}:[0-9]{2}(:[0-9]{2}(\.[0-9]+)?(Z|[+-][0-9]{2}:[0-9]{2}))?)?$`
+
+// schemaRequired lists the keys validate insists on, per type. Config itself
+// has none: with includes, title and groups may live in different files.
+var schemaRequired = map[string][]string{
+	"Group":    {"name"},
+	"Tile":     {"name"},
+	"Slot":     {"name", "check"},
+	"Check":    {"target"},
+	"Status":   {"id", "label"},
+	"Generate": {"command"},
+	"Banner":   {"src"},
+}
+
+// schemaDescriptions documents keys in editor tooltips, keyed by
+// "Type.key".
+var schemaDescriptions = map[string]string{
+	"Config.title":         "Dashboard title",
+	"Config.theme":         `"dark" (default), "light", "auto" or a theme from themes`,
+	"Config.template":      "Directory with dashboard.tmpl/style.css overriding the built-in ones",
+	"Config.themes":        "Custom themes: CSS custom properties (without the leading --) per theme name",
+	"Config.css":           "Extra stylesheets inlined after the built-in one",
+	"Config.defaults":      "Fallback values for slots that omit their own",
+	"Config.severity":      "Maps status ids to monitoring-plugin levels",
+	"Config.refresh":       "Auto-reload interval of the page, e.g. 1m",
+	"Config.include":       "Further config files or globs, relative to this file",
+	"Config.rule_sets":     "Named lists of rules that slots and defaults in any file can use with rule_set",
+	"Slot.rule_set":        "Name of a rule set under rule_sets to use instead of rules",
+	"Defaults.rule_set":    "Name of a rule set under rule_sets to use instead of rules",
+	"Group.discover":       "URL or command whose JSON/YAML output lists further tiles, fetched at generate time",
+	"Group.defaults":       "Fallback values for the group's slots, over the global defaults",
+	"Tile.defaults":        "Fallback values for the tile's slots, over the group's defaults",
+	"Defaults.headers":     "Added to the headers of http checks that don't set them",
+	"Group.maintenance":    "Planned windows during which the group's slots show the maintenance status",
+	"Tile.maintenance":     "Planned windows during which the tile's slots show the maintenance status",
+	"Maintenance.schedule": "Cron expression (minute hour day-of-month month day-of-week) for the start of each window, in local time",
+	"Maintenance.from":     "Start of a one-off window, e.g. 2024-05-01 22:00 (local time) or RFC 3339",
+	"Slot.depends_on":      `"Group/Tile/slot" paths of slots that must be OK for this slot to be checked`,
+	"Check.interval":       "Reuse the cached result of a previous run for this long instead of checking every run, e.g. 1h",
+	"Defaults.interval":    "Interval of checks that don't set one",
+	"Check.limits":         "Resource limits for the command and everything it starts (command checks only)",
+	"Limits.cpu":           "CPU time, e.g. 10s",
+	"Limits.memory":        "Virtual memory per process, e.g. 512M",
+	"Limits.processes":     "Processes of the user running ilias, counted across everything that user runs",
+	"Check.retries":        "Extra attempts when the check errors, exits non-zero or gets an HTTP 5xx",
+	"Tile.icon":            "Path or URL of the tile icon",
+	"Tile.link":            "URL the tile links to",
+	"Tile.generate":        "Command run before rendering the tile",
+	"Check.type":           "Inferred from the target when omitted",
+	"Check.target":         "URL, shell command, or list of program and arguments run without a shell; may reference ${ENV_VAR}, ${secret:ENV_VAR} and ${file:/path}",
+	"Check.shell":          "Shell running a string target with -c, default bash (command checks only)",
+	"Defaults.shell":       "Shell of command checks that don't set one, and of on_change commands",
+	"Generate.command":     "Shell command, or list of program and arguments run without a shell",
+	"Generate.shell":       "Shell running a string command with -c, default bash",
+	"Check.env":            "Environment variables for the command, over those of ilias (command checks only)",
+	"Check.clear_env":      "Pass only PATH and env to the command, not the environment of ilias (command checks only)",
+	"Check.workdir":        "Working directory of the command, relative to the config directory (command checks only)",
+	"Check.stdin":          "Text fed to the command's standard input (command checks only)",
+	"Defaults.env":         "Added to the env of command checks that don't set those variables",
+	"Generate.env":         "Environment variables for the command, over those of ilias",
+	"Generate.clear_env":   "Pass only PATH and env to the command, not the environment of ilias",
+	"Generate.workdir":     "Working directory of the command, relative to the config directory",
+	"Generate.stdin":       "Text fed to the command's standard input",
+	"Check.timeout":        "e.g. 10s",
+	"Check.method":         "HTTP method (http checks only), default GET",
+	"Check.headers":        "Extra request headers (http checks only)",
+	"Check.body":           "Request body (http checks only)",
+	"Match.code":           "Exit or HTTP status code, or a regex matched against it",
+	"Match.output":         "Regex matched against the check output",
+	"Rule.match":           "Conditions of the rule; an empty match is a catch-all",
+}
+
+// argsListSchema describes a command given as a list of program and
+// arguments (see Check.Args), and argsSchema either form of a command.
+var (
+	argsListSchema = map[string]any{"type": "array", "items": map[string]any{"type": "string"}, "minItems": 1}
+	argsSchema     = map[string]any{"oneOf": []any{map[string]any{"type": "string"}, argsListSchema}}
+)
+
+// schemaForms replaces the schema derived from the Go type of a key whose
+// custom unmarshaler accepts more, keyed by "Type.key".
+var schemaForms = map[string]map[string]any{
+	"Check.target":     argsSchema,
+	"Generate.command": argsSchema,
+}
+
+// schemaEnums restricts keys to fixed values, keyed by "Type.key".
+var schemaEnums = map[string][]any{
+	"Check.type":  {"http", "command"},
+	"Banner.type": {"image"},
+}
+
+type schemaBuilder struct {
+	defs map[string]any
+}
+
+// schema returns the schema for values of type t.
+func (s *schemaBuilder) schema(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t {
+	case durationType:
+		return map[string]any{"type": "string", "pattern": durationPattern}
+	case timestampType:
+		return map[string]any{"type": "string", "pattern": timestampPattern}
+	case byteSizeType:
+		return map[string]any{"oneOf": []any{
+			map[string]any{"type": "integer", "minimum": 0},
+			map[string]any{"type": "string", "pattern": byteSizePattern},
+		}}
+	case matchValueType:
+		return map[string]any{"oneOf": []any{
+			map[string]any{"type": "integer"},
+			map[string]any{"type": "string"},
+		}}
+	case stringListType:
+		return map[string]any{"oneOf": []any{
+			map[string]any{"type": "string"},
+			map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+		}}
+	case checkType:
+		// Shorthand: the target alone.
+		return map[string]any{"oneOf": []any{
+			map[string]any{"type": "string"},
+			argsListSchema,
+			s.ref(t),
+		}}
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		return s.ref(t)
+	case reflect.Slice:
+		return map[string]any{"type": "array", "items": s.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": s.schema(t.Elem())}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int64:
+		return map[string]any{"type": "integer"}
+	}
+	return map[string]any{}
+}
+
+// ref adds the object schema of a struct type to the definitions and
+// returns a reference to it.
+func (s *schemaBuilder) ref(t reflect.Type) map[string]any {
+	name := t.Name()
+	if _, ok := s.defs[name]; !ok {
+		s.defs[name] = nil // break cycles
+		s.defs[name] = s.object(t)
+	}
+	return map[string]any{"$ref": "#/definitions/" + name}
+}
+
+// object returns the schema of a struct type. Like strict mode, it allows
+// keys starting with "_".
+func (s *schemaBuilder) object(t reflect.Type) map[string]any {
+	props := make(map[string]any)
+	for key, ft := range yamlFields(t) {
+		id := t.Name() + "." + key
+		p := s.schema(ft)
+		if f, ok := schemaForms[id]; ok {
+			p = maps.Clone(f)
+		}
+		if d, ok := schemaDescriptions[id]; ok {
+			p["description"] = d
+		}
+		if e, ok := schemaEnums[id]; ok {
+			p["enum"] = e
+		}
+		props[key] = p
+	}
+	obj := map[string]any{
+		"type":                 "object",
+		"properties":           props,
+		"patternProperties":    map[string]any{"^_": map[string]any{}},
+		"additionalProperties": false,
+	}
+	if req, ok := schemaRequired[t.Name()]; ok {
+		obj["required"] = req
+	}
+	return obj
+}
+
+// addTemplates describes the templates section and "use" entries, which are
+// expanded before decoding and so have no Go types (see expandTemplates).
+func (s *schemaBuilder) addTemplates(root map[string]any) {
+	root["properties"].(map[string]any)["templates"] = map[string]any{
+		"type":                 "object",
+		"description":          "Tiles or slots with {{.param}} placeholders, instantiated with use/with",
+		"additionalProperties": map[string]any{"type": "object"},
+	}
+	s.defs["Use"] = map[string]any{
+		"type":     "object",
+		"required": []string{"use"},
+		"properties": map[string]any{
+			"use": map[string]any{"type": "string", "description": "Name of the template to instantiate"},
+			"with": map[string]any{
+				"description": "Parameters, or a list of parameter sets for one copy each",
+				"oneOf": []any{
+					map[string]any{"type": "object"},
+					map[string]any{"type": "array", "items": map[string]any{"type": "object"}},
+				},
+			},
+		},
+		// Other keys override the template's.
+		"additionalProperties": true,
+	}
+	for _, list := range []struct{ def, key string }{{"Group", "tiles"}, {"Tile", "slots"}} {
+		props := s.defs[list.def].(map[string]any)["properties"].(map[string]any)
+		items := props[list.key].(map[string]any)
+		// if/then/else rather than oneOf, so editors still point at the
+		// offending key of an ordinary tile or slot.
+		items["items"] = map[string]any{
+			"if":   map[string]any{"type": "object", "required": []string{"use"}},
+			"then": map[string]any{"$ref": "#/definitions/Use"},
+			"else": items["items"],
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// validateSchema is a minimal JSON Schema validator covering the keywords
+// Schema uses. It returns one message per violation.
+func validateSchema(root, schema map[string]any, v any, path string) []string {
+	if ref, ok := schema["$ref"].(string); ok {
+		name := strings.TrimPrefix(ref, "#/definitions/")
+		return validateSchema(root, root["definitions"].(map[string]any)[name].(map[string]any), v, path)
+	}
+	if cond, ok := schema["if"].(map[string]any); ok {
+		if len(validateSchema(root, cond, v, path)) == 0 {
+			return validateSchema(root, schema["then"].(map[string]any), v, path)
+		}
+		return validateSchema(root, schema["else"].(map[string]any), v, path)
+	}
+	if alts, ok := schema["oneOf"].([]any); ok {
+		matches := 0
+		for _, alt := range alts {
+			if len(validateSchema(root, alt.(map[string]any), v, path)) == 0 {
+				matches++
+			}
+		}
+		if matches != 1 {
+			return []string{fmt.Sprintf("%s: matches %d of oneOf", path, matches)}
+		}
+		return nil
+	}
+
+	var errs []string
+	switch schema["type"] {
+	case "object":
+		m, ok := v.(map[string]any)
+		if !ok {
+			return []string{fmt.Sprintf("%s: want object, got %T", path, v)}
+		}
+		props, _ := schema["properties"].(map[string]any)
+		patterns, _ := schema["patternProperties"].(map[string]any)
+		for key, val := range m {
+			if p, ok := props[key]; ok {
+				errs = append(errs, validateSchema(root, p.(map[string]any), val, path+"."+key)...)
+				continue
+			}
+			matched := false
+			for pattern := range patterns {
+				if regexp.MustCompile(pattern).MatchString(key) {
+					matched = true
+				}
+			}
+			if matched {
+				continue
+			}
+			switch ap := schema["additionalProperties"].(type) {
+			case bool:
+				if !ap {
+					errs = append(errs, fmt.Sprintf("%s: unexpected key %q", path, key))
+				}
+			case map[string]any:
+				errs = append(errs, validateSchema(root, ap, val, path+"."+key)...)
+			}
+		}
+		if req, ok := schema["required"].([]string); ok {
+			for _, key := range req {
+				if _, ok := m[key]; !ok {
+					errs = append(errs, fmt.Sprintf("%s: missing %q", path, key))
+				}
+			}
+		}
+	case "array":
+		items, ok := v.([]any)
+		if !ok {
+			return []string{fmt.Sprintf("%s: want array, got %T", path, v)}
+		}
+		for i, item := range items {
+			errs = append(errs, validateSchema(root, schema["items"].(map[string]any), item, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+	case "string":
+		s, ok := v.(string)
+		if !ok {
+			return []string{fmt.Sprintf("%s: want string, got %T", path, v)}
+		}
+		if p, ok := schema["pattern"].(string); ok && !regexp.MustCompile(p).MatchString(s) {
+			errs = append(errs, fmt.Sprintf("%s: %q does not match %s", path, s, p))
+		}
+	case "integer":
+		if _, ok := v.(int); !ok {
+			return []string{fmt.Sprintf("%s: want integer, got %T", path, v)}
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return []string{fmt.Sprintf("%s: want boolean, got %T", path, v)}
+		}
+	}
+	if enum, ok := schema["enum"].([]any); ok && !slices.Contains(enum, v) {
+		errs = append(errs, fmt.Sprintf("%s: %v is not one of %v", path, v, enum))
+	}
+	return errs
+}
+
+func validateYAML(t *testing.T, data []byte) []string {
+	t.Helper()
+	var v any
+	if err := yaml.Unmarshal(data, &v); err != nil {
+		t.Fatal(err)
+	}
+	root := Schema()
+	return validateSchema(root, root, v, "$")
+}
+
+func TestSchema_Testdata(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("..", "..", "testdata", "*.yaml"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no testdata: %v", err)
+	}
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, e := range validateYAML(t, data) {
+			t.Errorf("%s: %s", f, e)
+		}
+	}
+}
+
+func TestSchema_RejectsInvalidConfig(t *testing.T) {
+	yaml := `title: T
+groups:
+  - name: G
+    tiles:
+      - name: X
+        slots:
+          - name: a
+            check: { type: ftp, target: x, timout: 5s }
+            rules:
+              - match: { code: [1] }
+                status: { id: ok }
+`
+	errs := validateYAML(t, []byte(yaml))
+	joined := strings.Join(errs, "\n")
+	for _, want := range []string{
+		"$.groups[0].tiles[0].slots[0].check: matches 0 of oneOf",
+		"$.groups[0].tiles[0].slots[0].rules[0].match.code: matches 0 of oneOf",
+		`$.groups[0].tiles[0].slots[0].rules[0].status: missing "label"`,
+	} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("errors missing %q:\n%s", want, joined)
+		}
+	}
+}
+
+func TestSchema_IsJSON(t *testing.T) {
+	data, err := json.Marshal(Schema())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var back map[string]any
+	if err := json.Unmarshal(data, &back); err != nil {
+		t.Fatal(err)
+	}
+	if back["$schema"] == nil || back["definitions"] == nil {
+		t.Errorf("schema = %s", data)
+	}
+}
@@ -0,0 +1,170 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LoadStrict is like Load, but also rejects keys that ilias does not know
+// about, such as a misspelled "timout:". Keys starting with "_" (e.g.
+// _anchors) are left alone so they can hold YAML anchors and other notes.
+func LoadStrict(path string) (*Config, error) {
+	return load(path, true)
+}
+
+// ParseStrict is like Parse, but rejects unknown keys; see LoadStrict.
+func ParseStrict(data []byte) (*Config, error) {
+	return parse(data, true)
+}
+
+// Types with custom UnmarshalYAML methods whose YAML form differs from their
+// Go fields.
+var (
+	matchType      = reflect.TypeOf(Match{})
+	matchValueType = reflect.TypeOf(MatchValue{})
+	durationType   = reflect.TypeOf(Duration{})
+	timestampType  = reflect.TypeOf(Timestamp{})
+	byteSizeType   = reflect.TypeOf(ByteSize(0))
+	stringListType = reflect.TypeOf(StringList{})
+	checkType      = reflect.TypeOf(Check{})
+)
+
+// checkKeys reports every mapping key in n that has no matching field in t,
+// recursing into nested structs, slices and maps.
+func checkKeys(n *yaml.Node, t reflect.Type, path string, file string, errs *Errors) {
+	n = resolve(n)
+	if n == nil {
+		return
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == matchValueType, t == durationType, t == timestampType, t == byteSizeType, t == stringListType:
+		return
+	case t == checkType && n.Kind == yaml.ScalarNode:
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if n.Kind != yaml.MappingNode {
+			return
+		}
+		fields := yamlFields(t)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key := n.Content[i]
+			if strings.HasPrefix(key.Value, "_") || key.Value == "<<" {
+				continue
+			}
+			field, ok := fields[key.Value]
+			if !ok {
+				pos := nodePos(key)
+				pos.File = file
+				errs.add(pos, "%s", unknownKey(key.Value, path, fields))
+				continue
+			}
+			checkKeys(n.Content[i+1], field, join(path, key.Value), file, errs)
+		}
+	case reflect.Slice:
+		if n.Kind != yaml.SequenceNode {
+			return
+		}
+		for i, item := range n.Content {
+			checkKeys(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i), file, errs)
+		}
+	case reflect.Map:
+		if n.Kind != yaml.MappingNode {
+			return
+		}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			checkKeys(n.Content[i+1], t.Elem(), join(path, n.Content[i].Value), file, errs)
+		}
+	}
+}
+
+// yamlFields returns the YAML keys a struct accepts and their types.
+func yamlFields(t reflect.Type) map[string]reflect.Type {
+	if t == matchType {
+		// Match decodes through an auxiliary struct; see Match.UnmarshalYAML.
+		return map[string]reflect.Type{
+			"code":   reflect.TypeOf(MatchValue{}),
+			"output": reflect.TypeOf(""),
+		}
+	}
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if opts == "inline" {
+			maps.Copy(fields, yamlFields(f.Type))
+			continue
+		}
+		switch name {
+		case "-":
+			continue
+		case "":
+			name = strings.ToLower(f.Name)
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+// unknownKey formats the error for an unknown key, suggesting the closest
+// valid one if there is a plausible candidate.
+func unknownKey(key, path string, fields map[string]reflect.Type) string {
+	where := "at the top level"
+	if path != "" {
+		where = "in " + path
+	}
+	msg := fmt.Sprintf("unknown key %q %s", key, where)
+
+	best, bestDist := "", len(key)/2+1
+	for name := range fields {
+		if d := editDistance(strings.ToLower(key), name); d < bestDist || (d == bestDist && name < best) {
+			best, bestDist = name, d
+		}
+	}
+	if best != "" {
+		msg += fmt.Sprintf(" (did you mean %q?)", best)
+	}
+	return msg
+}
+
+// join appends a key to a dotted path.
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
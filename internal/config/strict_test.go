@@ -0,0 +1,92 @@
+package config
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseStrict_UnknownKeys(t *testing.T) {
+	yaml := `title: T
+titel: typo
+_anchors:
+  anything: { goes: here }
+defaults:
+  rule:
+    - match: {}
+      status: { id: ok, label: "✅" }
+groups:
+  - name: G
+    tiles:
+      - name: X
+        slots:
+          - name: a
+            check: { target: "true", timout: 5s }
+            rules:
+              - match: { ouput: x }
+                status: { id: ok, label: "✅", _note: ignored }
+`
+	if _, err := Parse([]byte(yaml)); err != nil {
+		t.Fatalf("Parse should ignore unknown keys, got %v", err)
+	}
+
+	_, err := ParseStrict([]byte(yaml))
+	var errs Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("err = %v, want Errors", err)
+	}
+	want := []string{
+		`line 2, column 1: unknown key "titel" at the top level (did you mean "title"?)`,
+		`line 6, column 3: unknown key "rule" in defaults (did you mean "rules"?)`,
+		`line 15, column 38: unknown key "timout" in groups[0].tiles[0].slots[0].check (did you mean "timeout"?)`,
+		`line 17, column 26: unknown key "ouput" in groups[0].tiles[0].slots[0].rules[0].match (did you mean "output"?)`,
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("got %d errors, want %d:\n%v", len(errs), len(want), err)
+	}
+	for i, w := range want {
+		if !strings.Contains(errs[i].Error(), w) {
+			t.Errorf("errs[%d] = %q, want to contain %q", i, errs[i], w)
+		}
+	}
+}
+
+func TestParseStrict_NoSuggestionForUnrelatedKey(t *testing.T) {
+	_, err := ParseStrict([]byte("title: T\nzzzzzz: 1\ngroups: [{ name: G, tiles: [{ name: X }] }]\n"))
+	if err == nil || !strings.Contains(err.Error(), `unknown key "zzzzzz" at the top level`) {
+		t.Fatalf("err = %v", err)
+	}
+	if strings.Contains(err.Error(), "did you mean") {
+		t.Errorf("err = %v, want no suggestion", err)
+	}
+}
+
+func TestLoadStrict_Testdata(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("..", "..", "testdata", "*.yaml"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no testdata: %v", err)
+	}
+	for _, f := range files {
+		if _, err := LoadStrict(f); err != nil {
+			t.Errorf("%s: %v", f, err)
+		}
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"timout", "timeout", 1},
+		{"rule", "rules", 1},
+		{"kitten", "sitting", 3},
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
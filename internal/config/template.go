@@ -0,0 +1,244 @@
+package config
+
+import (
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+// expandTemplates instantiates the top-level templates section of a
+// document. Entries of a group's tiles or a tile's slots that have a "use"
+// key are replaced by copies of the named template, one per parameter set
+// in "with":
+//
+//	templates:
+//	  disk:
+//	    name: "Disk ({{.mount}})"
+//	    slots:
+//	      - { name: usage, check: "df {{.mount}} --output=pcent | tail -1" }
+//	groups:
+//	  - name: System
+//	    tiles:
+//	      - use: disk
+//	        with: [{ mount: / }, { mount: /home }]
+//
+// "{{.param}}" placeholders in scalar values are filled in from "with". Only
+// parameters that "with" declares are placeholders, so other "{{...}}", such
+// as a docker --format, are kept as they are; a parameter that one set
+// declares and another leaves out is an error. Any other keys next to "use"
+// replace the template's keys of the same name (after substitution, too).
+//
+// Expansion works on the YAML nodes before decoding, so Config, strict mode
+// and validation only ever see ordinary tiles and slots. The templates
+// section is removed. Like anchors, templates are local to their file.
+func expandTemplates(doc *yaml.Node, file string) Errors {
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil
+	}
+	root := doc.Content[0]
+
+	x := &expander{templates: make(map[string]*yaml.Node), file: file}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value != "templates" {
+			continue
+		}
+		defs := resolve(root.Content[i+1])
+		if defs.Kind != yaml.MappingNode {
+			x.errorf(defs, "templates must be a mapping of template names to tiles or slots")
+		} else {
+			for j := 0; j+1 < len(defs.Content); j += 2 {
+				x.templates[defs.Content[j].Value] = resolve(defs.Content[j+1])
+			}
+		}
+		root.Content = append(root.Content[:i:i], root.Content[i+2:]...)
+		break
+	}
+
+	groups := mappingValue(root, "groups")
+	if groups == nil || groups.Kind != yaml.SequenceNode {
+		return x.errs
+	}
+	for _, g := range groups.Content {
+		tiles := mappingValue(g, "tiles")
+		x.expandList(tiles)
+		if tiles == nil || tiles.Kind != yaml.SequenceNode {
+			continue
+		}
+		for _, t := range tiles.Content {
+			x.expandList(mappingValue(t, "slots"))
+		}
+	}
+	return x.errs
+}
+
+type expander struct {
+	templates map[string]*yaml.Node
+	file      string
+	errs      Errors
+}
+
+func (x *expander) errorf(n *yaml.Node, format string, args ...any) {
+	pos := nodePos(n)
+	pos.File = x.file
+	x.errs.add(pos, format, args...)
+}
+
+// expandList replaces every "use" entry of a sequence with its instances.
+func (x *expander) expandList(list *yaml.Node) {
+	if list == nil || list.Kind != yaml.SequenceNode {
+		return
+	}
+	var out []*yaml.Node
+	for _, item := range list.Content {
+		if use := mappingValue(item, "use"); use != nil {
+			out = append(out, x.instantiate(resolve(item), use)...)
+			continue
+		}
+		out = append(out, item)
+	}
+	list.Content = out
+}
+
+// instantiate returns the copies of a template requested by a "use" entry.
+func (x *expander) instantiate(entry, use *yaml.Node) []*yaml.Node {
+	tmpl, ok := x.templates[use.Value]
+	if !ok {
+		x.errorf(use, "unknown template %q", use.Value)
+		return nil
+	}
+	if tmpl.Kind != yaml.MappingNode {
+		x.errorf(tmpl, "template %q must be a mapping", use.Value)
+		return nil
+	}
+
+	var sets []*yaml.Node
+	switch with := mappingValue(entry, "with"); {
+	case with == nil:
+		sets = []*yaml.Node{nil}
+	case with.Kind == yaml.MappingNode:
+		sets = []*yaml.Node{with}
+	case with.Kind == yaml.SequenceNode:
+		for _, set := range with.Content {
+			sets = append(sets, resolve(set))
+		}
+	default:
+		x.errorf(with, "with must be a mapping or a list of mappings")
+		return nil
+	}
+
+	// Every parameter declared by any set is a placeholder in all of them.
+	paramSets := make([]map[string]string, len(sets))
+	declared := make(map[string]bool)
+	for i, set := range sets {
+		params, ok := x.params(set)
+		if !ok {
+			continue
+		}
+		paramSets[i] = params
+		for k := range params {
+			declared[k] = true
+		}
+	}
+
+	var out []*yaml.Node
+	for i, set := range sets {
+		if paramSets[i] == nil {
+			continue
+		}
+		site := use
+		if set != nil {
+			site = set
+		}
+		sub := &substitution{name: use.Value, site: site, params: paramSets[i], declared: declared}
+		inst := x.copy(tmpl, sub)
+		for j := 0; j+1 < len(entry.Content); j += 2 {
+			key := entry.Content[j].Value
+			if key == "use" || key == "with" {
+				continue
+			}
+			setKey(inst, entry.Content[j], x.copy(entry.Content[j+1], sub))
+		}
+		out = append(out, inst)
+	}
+	return out
+}
+
+// substitution is one set of parameters applied to a template.
+type substitution struct {
+	name     string     // of the template
+	site     *yaml.Node // the parameter set, where missing parameters are reported
+	params   map[string]string
+	declared map[string]bool // by any set of the same "use"
+}
+
+// params reads one parameter set.
+func (x *expander) params(set *yaml.Node) (map[string]string, bool) {
+	params := make(map[string]string)
+	if set == nil {
+		return params, true
+	}
+	if set.Kind != yaml.MappingNode {
+		x.errorf(set, "template parameters must be a mapping")
+		return nil, false
+	}
+	for i := 0; i+1 < len(set.Content); i += 2 {
+		value := resolve(set.Content[i+1])
+		if value.Kind != yaml.ScalarNode {
+			x.errorf(value, "template parameter %q must be a scalar", set.Content[i].Value)
+			return nil, false
+		}
+		params[set.Content[i].Value] = value.Value
+	}
+	return params, true
+}
+
+// placeholderRe matches "{{.param}}", with optional spaces inside the braces.
+var placeholderRe = regexp.MustCompile(`\{\{\s*\.([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+// copy deep-copies n, filling in placeholders in scalar values. Aliases are
+// kept as they are, so anchored fragments are shared rather than expanded.
+func (x *expander) copy(n *yaml.Node, s *substitution) *yaml.Node {
+	c := *n
+	if n.Kind == yaml.ScalarNode {
+		c.Value = x.substitute(n, s)
+	}
+	if n.Kind == yaml.AliasNode || len(n.Content) == 0 {
+		return &c
+	}
+	c.Content = make([]*yaml.Node, len(n.Content))
+	for i, child := range n.Content {
+		if n.Kind == yaml.MappingNode && i%2 == 0 {
+			key := *child
+			c.Content[i] = &key
+			continue
+		}
+		c.Content[i] = x.copy(child, s)
+	}
+	return &c
+}
+
+func (x *expander) substitute(n *yaml.Node, s *substitution) string {
+	return placeholderRe.ReplaceAllStringFunc(n.Value, func(m string) string {
+		param := placeholderRe.FindStringSubmatch(m)[1]
+		if !s.declared[param] {
+			return m
+		}
+		v, ok := s.params[param]
+		if !ok {
+			x.errorf(s.site, "template %q, line %d: parameter %q is not set", s.name, n.Line, param)
+			return m
+		}
+		return v
+	})
+}
+
+// setKey sets key to value in a mapping node, replacing an existing entry.
+func setKey(m, key, value *yaml.Node) {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key.Value {
+			m.Content[i+1] = value
+			return
+		}
+	}
+	m.Content = append(m.Content, key, value)
+}
@@ -0,0 +1,148 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+const templateYAML = `title: T
+defaults:
+  rules:
+    - match: {}
+      status: { id: ok, label: "✅" }
+templates:
+  disk:
+    name: "Disk ({{.label}})"
+    slots:
+      - name: usage
+        check: "df {{.mount}}"
+  http:
+    name: "{{.name}}"
+    check: { target: "https://{{.host}}/health", timeout: 5s }
+groups:
+  - name: System
+    tiles:
+      - name: Uptime
+        slots: [{ name: up, check: uptime }]
+      - use: disk
+        with:
+          - { mount: /, label: root }
+          - { mount: /home, label: home }
+      - use: disk
+        with: { mount: /srv, label: srv }
+        icon: "icons/{{.label}}.png"
+      - name: Web
+        slots:
+          - use: http
+            with: [{ name: api, host: api.example.com }, { name: www, host: example.com }]
+`
+
+func TestParse_Templates(t *testing.T) {
+	cfg, err := ParseStrict([]byte(templateYAML))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tiles := cfg.Groups[0].Tiles
+	var names []string
+	for _, tile := range tiles {
+		names = append(names, tile.Name)
+	}
+	if got, want := strings.Join(names, ","), "Uptime,Disk (root),Disk (home),Disk (srv),Web"; got != want {
+		t.Fatalf("tiles = %s, want %s", got, want)
+	}
+	if got := tiles[2].Slots[0].Check.Target; got != "df /home" {
+		t.Errorf("home check = %q, want df /home", got)
+	}
+	if got := tiles[3].Icon; got != "icons/srv.png" {
+		t.Errorf("override icon = %q, want icons/srv.png", got)
+	}
+	if got := tiles[1].Slots[0].RulesFrom; got != "defaults" {
+		t.Errorf("rules from %q, want defaults", got)
+	}
+	if tiles[2].Pos.Line != 8 {
+		t.Errorf("instance position = %v, want the template (line 8)", tiles[2].Pos)
+	}
+
+	web := tiles[4].Slots
+	if len(web) != 2 || web[0].Name != "api" || web[1].Check.Target != "https://example.com/health" {
+		t.Errorf("web slots = %+v", web)
+	}
+}
+
+func TestParse_TemplateKeepsOtherBraces(t *testing.T) {
+	cfg, err := Parse([]byte(`title: T
+defaults:
+  rules: [{ match: {}, status: { id: ok, label: "✅" } }]
+templates:
+  docker:
+    name: "{{ .host }}"
+    slots: [{ name: up, check: "docker -H {{.host}} ps --format '{{.Names}} {{json .Labels}}'" }]
+groups:
+  - name: G
+    tiles:
+      - { use: docker, with: { host: nas } }
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tile := cfg.Groups[0].Tiles[0]
+	if tile.Name != "nas" {
+		t.Errorf("name = %q, want nas", tile.Name)
+	}
+	if got, want := tile.Slots[0].Check.Target, "docker -H nas ps --format '{{.Names}} {{json .Labels}}'"; got != want {
+		t.Errorf("check = %q, want %q", got, want)
+	}
+}
+
+func TestParse_TemplateErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{
+			name: "unknown template",
+			yaml: `groups:
+  - name: G
+    tiles:
+      - use: nope
+`,
+			wantErr: `line 4, column 14: unknown template "nope"`,
+		},
+		{
+			name: "missing parameter",
+			yaml: `templates:
+  t: { name: "{{.name}}", slots: [{ name: s, check: "ping {{.host}}" }] }
+groups:
+  - name: G
+    tiles:
+      - use: t
+        with: [{ name: x, host: a }, { name: y }]
+`,
+			wantErr: `line 7, column 38: template "t", line 2: parameter "host" is not set`,
+		},
+		{
+			name: "with is a scalar",
+			yaml: `templates:
+  t: { name: x, slots: [{ name: s, check: "true" }] }
+groups:
+  - name: G
+    tiles:
+      - { use: t, with: nope }
+`,
+			wantErr: "line 6, column 25: with must be a mapping or a list of mappings",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.yaml))
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %q, want to contain %q", err.Error(), tt.wantErr)
+			}
+		})
+	}
+}
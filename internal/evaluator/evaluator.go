@@ -2,6 +2,7 @@
 package evaluator
 
 import (
+	"fmt"
 	"strconv"
 
 	"github.com/halfdane/ilias/internal/checker"
@@ -25,6 +26,83 @@ func Evaluate(result checker.Result, rules []config.Rule) config.Status {
 	return BuiltinErrorStatus
 }
 
+// ConditionTrace records the outcome of a single match condition.
+type ConditionTrace struct {
+	Field   string // "code" or "output"
+	Pattern string // the configured value, e.g. "200" or `5\d\d`
+	Matched bool
+	Reason  string // why the condition failed; empty when it matched
+}
+
+// RuleTrace records how a single rule was evaluated.
+type RuleTrace struct {
+	Index      int
+	Rule       config.Rule
+	Conditions []ConditionTrace // empty for a catch-all rule
+	Matched    bool
+}
+
+// Explain evaluates the rules like Evaluate, additionally returning a trace of
+// every rule that was considered. Evaluation stops at the first matching
+// rule, so the winning rule (if any) is the last trace entry.
+func Explain(result checker.Result, rules []config.Rule) (config.Status, []RuleTrace) {
+	var traces []RuleTrace
+	for i, rule := range rules {
+		tr := traceRule(result, rule.Match)
+		tr.Index = i
+		tr.Rule = rule
+		traces = append(traces, tr)
+		if tr.Matched {
+			return rule.Status, traces
+		}
+	}
+	return BuiltinErrorStatus, traces
+}
+
+// traceRule evaluates every condition of a match and records the outcome.
+// It must agree with matchesRule.
+func traceRule(result checker.Result, match config.Match) RuleTrace {
+	tr := RuleTrace{Matched: true}
+
+	if match.Code != nil {
+		ct := ConditionTrace{Field: "code", Pattern: codePattern(match.Code)}
+		switch {
+		case result.Err != nil:
+			ct.Reason = "check errored, no code to match"
+		case !matchCode(result.Code, match.Code):
+			ct.Reason = fmt.Sprintf("got %d", result.Code)
+		default:
+			ct.Matched = true
+		}
+		tr.Conditions = append(tr.Conditions, ct)
+		tr.Matched = tr.Matched && ct.Matched
+	}
+
+	if match.Output != nil {
+		ct := ConditionTrace{Field: "output", Pattern: match.Output.String()}
+		if match.Output.MatchString(result.Output) {
+			ct.Matched = true
+		} else {
+			ct.Reason = "output does not match"
+		}
+		tr.Conditions = append(tr.Conditions, ct)
+		tr.Matched = tr.Matched && ct.Matched
+	}
+
+	return tr
+}
+
+// codePattern renders a code condition as it appears in the config.
+func codePattern(mv *config.MatchValue) string {
+	if mv.Exact != nil {
+		return strconv.Itoa(*mv.Exact)
+	}
+	if mv.Regex != nil {
+		return mv.Regex.String()
+	}
+	return ""
+}
+
 // matchesRule checks whether a result satisfies a match condition.
 // An empty match (no code, no output) is a catch-all that always matches.
 func matchesRule(result checker.Result, match config.Match) bool {
@@ -215,3 +215,85 @@ func TestEvaluate_CheckError_OutputRuleMatches(t *testing.T) {
 		t.Errorf("status = %q, want %q (output rule should match on TLS error)", status.ID, "cert-error")
 	}
 }
+
+func TestExplain_TracesUntilFirstMatch(t *testing.T) {
+	rules := []config.Rule{
+		{
+			Match: config.Match{
+				Code:   &config.MatchValue{Exact: intPtr(0)},
+				Output: regexp.MustCompile("^fine"),
+			},
+			Status: config.Status{ID: "ok", Label: "✅"},
+		},
+		{
+			Match:  config.Match{Code: &config.MatchValue{Regex: regexp.MustCompile("1|2")}},
+			Status: config.Status{ID: "warn", Label: "⚠️"},
+		},
+		{
+			Match:  config.Match{},
+			Status: config.Status{ID: "error", Label: "❌"},
+		},
+	}
+
+	status, traces := Explain(checker.Result{Code: 1, Output: "hi"}, rules)
+	if status.ID != "warn" {
+		t.Errorf("status = %q, want %q", status.ID, "warn")
+	}
+	if len(traces) != 2 {
+		t.Fatalf("len(traces) = %d, want 2 (evaluation stops at the winner)", len(traces))
+	}
+
+	first := traces[0]
+	if first.Matched || len(first.Conditions) != 2 {
+		t.Fatalf("rule[0] trace = %+v, want two failed conditions", first)
+	}
+	if c := first.Conditions[0]; c.Field != "code" || c.Pattern != "0" || c.Matched || c.Reason != "got 1" {
+		t.Errorf("rule[0] code condition = %+v", c)
+	}
+	if c := first.Conditions[1]; c.Field != "output" || c.Matched {
+		t.Errorf("rule[0] output condition = %+v", c)
+	}
+
+	if second := traces[1]; !second.Matched || second.Index != 1 || second.Conditions[0].Pattern != "1|2" {
+		t.Errorf("rule[1] trace = %+v, want matched code regex", second)
+	}
+}
+
+func TestExplain_AgreesWithEvaluate(t *testing.T) {
+	rules := []config.Rule{
+		{
+			Match:  config.Match{Code: &config.MatchValue{Exact: intPtr(0)}},
+			Status: config.Status{ID: "ok", Label: "✅"},
+		},
+		{
+			Match:  config.Match{Output: regexp.MustCompile("refused")},
+			Status: config.Status{ID: "down", Label: "🔴"},
+		},
+	}
+
+	results := []checker.Result{
+		{Code: 0},
+		{Code: 0, Err: errors.New("boom")},
+		{Code: -1, Output: "connection refused", Err: errors.New("refused")},
+		{Code: 3, Output: "nope"},
+	}
+	for _, r := range results {
+		want := Evaluate(r, rules)
+		got, traces := Explain(r, rules)
+		if got != want {
+			t.Errorf("Explain(%+v) = %q, Evaluate = %q", r, got.ID, want.ID)
+		}
+		if len(traces) == 0 {
+			t.Errorf("Explain(%+v) returned no traces", r)
+		}
+	}
+}
+
+func TestExplain_CatchAllHasNoConditions(t *testing.T) {
+	rules := []config.Rule{{Match: config.Match{}, Status: config.Status{ID: "ok", Label: "✅"}}}
+
+	_, traces := Explain(checker.Result{Err: errors.New("timeout")}, rules)
+	if len(traces) != 1 || !traces[0].Matched || len(traces[0].Conditions) != 0 {
+		t.Errorf("traces = %+v, want one matched catch-all", traces)
+	}
+}
@@ -0,0 +1,132 @@
+// Package fixture runs canned check results through a config's rules so rule
+// sets can be tested offline, without executing any checks.
+package fixture
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/halfdane/ilias/internal/checker"
+	"github.com/halfdane/ilias/internal/config"
+	"github.com/halfdane/ilias/internal/evaluator"
+)
+
+// Case is a canned check result and the status id it is expected to produce.
+type Case struct {
+	Name   string `yaml:"name,omitempty"`
+	Code   int    `yaml:"code,omitempty"`
+	Output string `yaml:"output,omitempty"`
+	Error  string `yaml:"error,omitempty"` // non-empty simulates a failed check
+	Expect string `yaml:"expect"`
+}
+
+// Result converts the case into the checker.Result it simulates.
+func (c Case) Result() checker.Result {
+	r := checker.Result{Code: c.Code, Output: c.Output}
+	if c.Error != "" {
+		r.Err = errors.New(c.Error)
+	}
+	return r
+}
+
+// Suite holds all cases for one slot.
+type Suite struct {
+	Slot  string // "Group/Tile/slot"
+	Cases []Case
+}
+
+// Load reads a fixture file from the given path.
+func Load(path string) ([]Suite, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading fixture file: %w", err)
+	}
+	return Parse(data)
+}
+
+// Parse parses fixture YAML: a mapping from slot path to a list of cases.
+// Slots are returned in file order.
+//
+//	"Network/Gateway/ping":
+//	  - name: reachable
+//	    code: 0
+//	    expect: ok
+func Parse(data []byte) ([]Suite, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parsing fixtures: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("fixtures: line %d: expected a mapping of slot paths to cases", root.Line)
+	}
+
+	var suites []Suite
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		s := Suite{Slot: key.Value}
+		if err := value.Decode(&s.Cases); err != nil {
+			return nil, fmt.Errorf("fixtures: %q: %w", s.Slot, err)
+		}
+		for ci, c := range s.Cases {
+			if c.Expect == "" {
+				return nil, fmt.Errorf("fixtures: %q, case[%d]: expect is required", s.Slot, ci)
+			}
+		}
+		suites = append(suites, s)
+	}
+	return suites, nil
+}
+
+// Failure describes a case whose evaluated status differs from the expected
+// one, or a suite whose slot does not exist.
+type Failure struct {
+	Slot   string
+	Index  int
+	Case   Case
+	Got    config.Status
+	Traces []evaluator.RuleTrace
+	Err    error // set when the slot could not be found
+}
+
+// Report is the outcome of running fixture suites.
+type Report struct {
+	Passed   int
+	Failures []Failure
+}
+
+// Run evaluates every case against the effective rules of its slot.
+func Run(cfg *config.Config, suites []Suite) Report {
+	var rep Report
+	for _, s := range suites {
+		_, _, slot, ok := cfg.FindSlot(s.Slot)
+		if !ok {
+			rep.Failures = append(rep.Failures, Failure{
+				Slot: s.Slot,
+				Err:  fmt.Errorf("no slot %q in config", s.Slot),
+			})
+			continue
+		}
+		for ci, c := range s.Cases {
+			got, traces := evaluator.Explain(c.Result(), slot.Rules)
+			if got.ID == c.Expect {
+				rep.Passed++
+				continue
+			}
+			rep.Failures = append(rep.Failures, Failure{
+				Slot:   s.Slot,
+				Index:  ci,
+				Case:   c,
+				Got:    got,
+				Traces: traces,
+			})
+		}
+	}
+	return rep
+}
@@ -0,0 +1,105 @@
+package fixture
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/halfdane/ilias/internal/config"
+)
+
+const testConfig = `
+title: Test
+defaults:
+  rules:
+    - match: { code: 0 }
+      status: { id: ok, label: "✅" }
+    - match: {}
+      status: { id: error, label: "❌" }
+groups:
+  - name: Network
+    tiles:
+      - name: Web
+        slots:
+          - name: https
+            check: https://example.com
+            rules:
+              - match: { code: 200 }
+                status: { id: ok, label: "✅" }
+              - match: { output: "certificate" }
+                status: { id: cert, label: "🔒" }
+              - match: {}
+                status: { id: down, label: "🔴" }
+      - name: Gateway
+        slots:
+          - name: ping
+            check: "ping -c1 gw"
+`
+
+func TestParse_KeepsFileOrder(t *testing.T) {
+	suites, err := Parse([]byte(`
+"Network/Web/https":
+  - { code: 200, expect: ok }
+"Network/Gateway/ping":
+  - { name: up, expect: ok }
+  - { name: down, code: 1, expect: error }
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(suites) != 2 || suites[0].Slot != "Network/Web/https" || suites[1].Slot != "Network/Gateway/ping" {
+		t.Fatalf("suites = %+v", suites)
+	}
+	if len(suites[1].Cases) != 2 || suites[1].Cases[1].Code != 1 {
+		t.Errorf("ping cases = %+v", suites[1].Cases)
+	}
+}
+
+func TestParse_MissingExpect(t *testing.T) {
+	_, err := Parse([]byte(`"G/T/s": [{ code: 0 }]`))
+	if err == nil || !strings.Contains(err.Error(), "expect is required") {
+		t.Errorf("err = %v, want missing expect error", err)
+	}
+}
+
+func TestRun(t *testing.T) {
+	cfg, err := config.Parse([]byte(testConfig))
+	if err != nil {
+		t.Fatalf("parsing config: %v", err)
+	}
+	suites, err := Parse([]byte(`
+"Network/Web/https":
+  - { name: up, code: 200, expect: ok }
+  - name: bad cert
+    output: "x509: certificate signed by unknown authority"
+    error: "tls handshake failed"
+    expect: cert
+  - { name: wrong, code: 503, expect: ok }
+"Network/Gateway/ping":
+  - { name: defaults apply, code: 1, expect: error }
+"Network/Nope/x":
+  - { expect: ok }
+`))
+	if err != nil {
+		t.Fatalf("parsing fixtures: %v", err)
+	}
+
+	rep := Run(cfg, suites)
+	if rep.Passed != 3 {
+		t.Errorf("passed = %d, want 3", rep.Passed)
+	}
+	if len(rep.Failures) != 2 {
+		t.Fatalf("failures = %+v, want 2", rep.Failures)
+	}
+
+	f := rep.Failures[0]
+	if f.Case.Name != "wrong" || f.Got.ID != "down" || f.Index != 2 {
+		t.Errorf("failure[0] = %+v, want case 'wrong' evaluating to down", f)
+	}
+	if len(f.Traces) != 3 {
+		t.Errorf("failure[0] traces = %d, want 3", len(f.Traces))
+	}
+
+	if rep.Failures[1].Err == nil {
+		t.Error("failure[1] should report the unknown slot")
+	}
+}
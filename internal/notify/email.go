@@ -0,0 +1,171 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/halfdane/ilias/internal/config"
+)
+
+// defaultEmailTimeout bounds a whole SMTP conversation.
+const defaultEmailTimeout = 30 * time.Second
+
+// email tells every recipient about the changes for them, in order.
+// Recipients who are told about the same changes share one message, so
+// each message goes out once, to all of them.
+func (n *Notifier) email(ctx context.Context, e *config.Email, changes []Change) error {
+	byRecipient := make(map[string][]int)
+	var recipients []string
+	for i, c := range changes {
+		for _, to := range e.Recipients(c.Group) {
+			if _, ok := byRecipient[to]; !ok {
+				recipients = append(recipients, to)
+			}
+			byRecipient[to] = append(byRecipient[to], i)
+		}
+	}
+
+	// Group the recipients by their changes, keyed by the changes' indices.
+	byChanges := make(map[string][]string)
+	var keys []string
+	for _, to := range recipients {
+		key := fmt.Sprint(byRecipient[to])
+		if _, ok := byChanges[key]; !ok {
+			keys = append(keys, key)
+		}
+		byChanges[key] = append(byChanges[key], to)
+	}
+
+	var errs []string
+	for _, key := range keys {
+		to := byChanges[key]
+		var cs []Change
+		for _, i := range byRecipient[to[0]] {
+			cs = append(cs, changes[i])
+		}
+		msg := n.message(e.From, to, cs)
+		if err := sendMail(ctx, e, to, msg); err != nil {
+			errs = append(errs, fmt.Sprintf("to %s: %v", strings.Join(to, ", "), err))
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// message formats changes as a plain-text email to all of to.
+func (n *Notifier) message(from string, to []string, changes []Change) []byte {
+	subject := fmt.Sprintf("%d status changes", len(changes))
+	if len(changes) == 1 {
+		c := changes[0]
+		subject = fmt.Sprintf("%s: %s → %s", c.path(), c.Old.Label, c.New.Label)
+	}
+	if n.Config.Title != "" {
+		subject = n.Config.Title + ": " + subject
+	}
+
+	var body bytes.Buffer
+	qp := quotedprintable.NewWriter(&body)
+	for i, c := range changes {
+		if i > 0 {
+			fmt.Fprint(qp, "\r\n")
+		}
+		fmt.Fprintf(qp, "%s\r\n  %s %s → %s %s at %s\r\n", c.path(), c.Old.Label, c.Old.ID, c.New.Label, c.New.ID, c.Time.Format(time.RFC3339))
+		if c.Output != "" {
+			for _, line := range strings.Split(c.Output, "\n") {
+				fmt.Fprintf(qp, "    %s\r\n", line)
+			}
+		}
+	}
+	qp.Close()
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", changes[0].Time.Format(time.RFC1123Z))
+	fmt.Fprint(&msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprint(&msg, "Content-Type: text/plain; charset=utf-8\r\n")
+	fmt.Fprint(&msg, "Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+	msg.Write(body.Bytes())
+	return msg.Bytes()
+}
+
+// sendMail delivers one message to every address of to. Unlike smtp.SendMail it has a timeout and
+// lets the config require or refuse STARTTLS; when StartTLS is unset it is
+// used if the server offers it.
+func sendMail(ctx context.Context, e *config.Email, to []string, msg []byte) error {
+	timeout := e.Timeout.Duration
+	if timeout == 0 {
+		timeout = defaultEmailTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	host, _, err := net.SplitHostPort(e.Server)
+	if err != nil {
+		return err
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", e.Server)
+	if err != nil {
+		return err
+	}
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if e.StartTLS == nil || *e.StartTLS {
+		ok, _ := c.Extension("STARTTLS")
+		if ok {
+			if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+				return fmt.Errorf("starttls: %w", err)
+			}
+		} else if e.StartTLS != nil {
+			return errors.New("server does not support STARTTLS")
+		}
+	}
+	if e.Username != "" {
+		// PlainAuth refuses to send the password unencrypted, except to
+		// localhost.
+		if err := c.Auth(smtp.PlainAuth("", e.Username, e.Password, host)); err != nil {
+			return fmt.Errorf("auth: %w", err)
+		}
+	}
+
+	if err := c.Mail(e.From); err != nil {
+		return err
+	}
+	for _, addr := range to {
+		if err := c.Rcpt(addr); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
@@ -0,0 +1,172 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"io"
+	"mime/quotedprintable"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/halfdane/ilias/internal/config"
+)
+
+// fakeSMTP is a minimal SMTP server that accepts every message.
+type fakeSMTP struct {
+	addr string
+
+	mu    sync.Mutex
+	auth  []string // decoded AUTH PLAIN credentials
+	mails []mail
+}
+
+type mail struct {
+	from, data string
+	to         []string // RCPT commands
+}
+
+func startSMTP(t *testing.T) *fakeSMTP {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	s := &fakeSMTP{addr: ln.Addr().String()}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeSMTP) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+	reply("220 localhost ESMTP fake")
+
+	var m mail
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.TrimRight(line, "\r\n")
+		switch verb := strings.ToUpper(strings.Fields(cmd + " ")[0]); verb {
+		case "EHLO", "HELO":
+			reply("250-localhost")
+			reply("250 AUTH PLAIN")
+		case "AUTH":
+			creds, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(cmd, "AUTH PLAIN "))
+			s.mu.Lock()
+			s.auth = append(s.auth, strings.ReplaceAll(string(creds), "\x00", "|"))
+			s.mu.Unlock()
+			reply("235 ok")
+		case "MAIL":
+			m = mail{from: cmd}
+			reply("250 ok")
+		case "RCPT":
+			m.to = append(m.to, cmd)
+			reply("250 ok")
+		case "DATA":
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil || l == ".\r\n" {
+					break
+				}
+				data.WriteString(l)
+			}
+			m.data = data.String()
+			s.mu.Lock()
+			s.mails = append(s.mails, m)
+			s.mu.Unlock()
+			reply("250 queued")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 not implemented")
+		}
+	}
+}
+
+func TestSend_EmailBatchesPerRecipient(t *testing.T) {
+	srv := startSMTP(t)
+	cfg := &config.Config{Title: "Home", Notify: &config.Notify{Email: &config.Email{
+		Server:   srv.addr,
+		Username: "ilias",
+		Password: "hunter22",
+		From:     "ilias@example.com",
+		To:       config.StringList{"ops@example.com", "noc@example.com"},
+		Groups:   map[string]config.StringList{"Storage": {"storage@example.com", "ops@example.com", "noc@example.com"}},
+	}}}
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	changes := []Change{
+		{Group: "Web", Tile: "Site", Slot: "up", Old: ok, New: down, Output: "HTTP 503", Time: now},
+		{Group: "Storage", Tile: "NAS", Slot: "disk", Old: down, New: ok, Time: now},
+		{Group: "Web", Tile: "API", Slot: "up", Old: ok, New: down, Time: now},
+	}
+
+	if err := (&Notifier{Config: cfg}).Send(context.Background(), changes); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	if len(srv.mails) != 2 {
+		t.Fatalf("got %d mails, want one per set of changes: %+v", len(srv.mails), srv.mails)
+	}
+	byTo := map[string]string{}
+	for _, m := range srv.mails {
+		if m.from != "MAIL FROM:<ilias@example.com>" {
+			t.Errorf("from = %q", m.from)
+		}
+		body, err := io.ReadAll(quotedprintable.NewReader(strings.NewReader(m.data[strings.Index(m.data, "\r\n\r\n")+4:])))
+		if err != nil {
+			t.Fatal(err)
+		}
+		byTo[strings.Join(m.to, " ")] = m.data[:strings.Index(m.data, "\r\n\r\n")] + "\n" + string(body)
+	}
+
+	ops := byTo["RCPT TO:<ops@example.com> RCPT TO:<noc@example.com>"]
+	for _, want := range []string{"To: ops@example.com, noc@example.com\r\n", "Subject: Home: 3 status changes", "Web/Site/up\r\n  ✅ ok → 🔴 down at 2024-05-01T12:00:00Z", "    HTTP 503", "Storage/NAS/disk", "Web/API/up"} {
+		if !strings.Contains(ops, want) {
+			t.Errorf("ops mail missing %q:\n%s", want, ops)
+		}
+	}
+	storage := byTo["RCPT TO:<storage@example.com>"]
+	if !strings.Contains(storage, "Subject: =?utf-8?q?Home:_Storage/NAS/disk:_=F0=9F=94=B4_=E2=86=92_=E2=9C=85?=") || strings.Contains(storage, "Web/") {
+		t.Errorf("storage mail should only have the Storage change:\n%s", storage)
+	}
+	if len(srv.auth) != 2 || srv.auth[0] != "|ilias|hunter22" {
+		t.Errorf("auth = %q, want PLAIN ilias/hunter22 per connection", srv.auth)
+	}
+}
+
+func TestSend_EmailRequiresStartTLS(t *testing.T) {
+	srv := startSMTP(t)
+	required := true
+	cfg := &config.Config{Notify: &config.Notify{Email: &config.Email{
+		Server:   srv.addr,
+		StartTLS: &required,
+		From:     "ilias@example.com",
+		To:       config.StringList{"ops@example.com"},
+	}}}
+	err := (&Notifier{Config: cfg}).Send(context.Background(), []Change{{Group: "G", Tile: "T", Slot: "s", Old: ok, New: down}})
+	if err == nil || !strings.Contains(err.Error(), "notify.email to ops@example.com: server does not support STARTTLS") {
+		t.Errorf("err = %v, want STARTTLS error", err)
+	}
+	if len(srv.mails) != 0 {
+		t.Errorf("mail sent without STARTTLS: %+v", srv.mails)
+	}
+}
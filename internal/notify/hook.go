@@ -0,0 +1,40 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/halfdane/ilias/internal/checker"
+)
+
+// hookTimeout bounds an on_change command.
+const hookTimeout = 60 * time.Second
+
+// hook runs a slot's on_change command with shell (bash if empty) for a
+// change, with the change in the environment.
+func hook(ctx context.Context, shell, command string, c Change) error {
+	ctx, cancel := context.WithTimeout(ctx, hookTimeout)
+	defer cancel()
+
+	cmd := checker.Shell(ctx, shell, command)
+	cmd.Env = append(os.Environ(),
+		"ILIAS_GROUP="+c.Group,
+		"ILIAS_TILE="+c.Tile,
+		"ILIAS_SLOT="+c.Slot,
+		"ILIAS_OLD_STATUS="+c.Old.ID,
+		"ILIAS_NEW_STATUS="+c.New.ID,
+		"ILIAS_OUTPUT="+c.Output,
+	)
+	out, err := cmd.CombinedOutput()
+	checker.KillLeftovers(cmd)
+	if err != nil {
+		if out := strings.TrimSpace(string(out)); out != "" {
+			return fmt.Errorf("%w: %s", err, excerpt(out))
+		}
+		return err
+	}
+	return nil
+}
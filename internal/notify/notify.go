@@ -0,0 +1,137 @@
+// Package notify tells the outside world about slots whose status changed
+// since the previous run.
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+	"unicode/utf8"
+
+	"github.com/halfdane/ilias/internal/config"
+	"github.com/halfdane/ilias/internal/runner"
+	"github.com/halfdane/ilias/internal/state"
+)
+
+// maxExcerpt is the most check output included in a notification.
+const maxExcerpt = 1024
+
+// Change is a slot whose status id differs from the previous run.
+type Change struct {
+	Group  string
+	Tile   string
+	Slot   string
+	Old    config.Status
+	New    config.Status
+	Output string // the start of the check output
+	Time   time.Time
+}
+
+// Changes compares the slots of result with the statuses of the previous
+// run. Slots that did not exist before are not changes, so the first run
+// (prev is nil) notifies nothing. Slots in maintenance, unreachable or
+// timed out are left out.
+func Changes(prev state.Statuses, result *runner.DashboardResult, now time.Time) []Change {
+	var changes []Change
+	for _, g := range result.Groups {
+		for _, t := range g.Tiles {
+			for _, s := range t.Slots {
+				old, ok := prev[config.SlotPath(g.Name, t.Name, s.Name)]
+				if !ok || s.Suppressed() || s.TimedOut || old.ID == s.Status.ID {
+					continue
+				}
+				changes = append(changes, Change{
+					Group:  g.Name,
+					Tile:   t.Name,
+					Slot:   s.Name,
+					Old:    old,
+					New:    s.Status,
+					Output: excerpt(s.Output),
+					Time:   now,
+				})
+			}
+		}
+	}
+	return changes
+}
+
+// Statuses returns the statuses to save after a run. Slots in maintenance,
+// unreachable or timed out keep their status from prev, so changes that
+// happened in the meantime are reported once they are checked again.
+func Statuses(prev state.Statuses, result *runner.DashboardResult) state.Statuses {
+	s := make(state.Statuses)
+	for _, g := range result.Groups {
+		for _, t := range g.Tiles {
+			for _, slot := range t.Slots {
+				path := config.SlotPath(g.Name, t.Name, slot.Name)
+				if !slot.Suppressed() && !slot.TimedOut {
+					s[path] = slot.Status
+				} else if old, ok := prev[path]; ok {
+					s[path] = old
+				}
+			}
+		}
+	}
+	return s
+}
+
+func (c Change) path() string {
+	return config.SlotPath(c.Group, c.Tile, c.Slot)
+}
+
+// excerpt cuts s to at most maxExcerpt bytes, at a character boundary.
+func excerpt(s string) string {
+	if len(s) <= maxExcerpt {
+		return s
+	}
+	cut := maxExcerpt
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + "…"
+}
+
+// Notifier sends the notifications configured in a Config.
+type Notifier struct {
+	Config *config.Config
+	// Client is used for webhooks; nil means http.DefaultClient with the
+	// webhook's timeout.
+	Client *http.Client
+}
+
+// Send notifies every configured target about changes: each webhook once per
+// change, each email recipient once for all of them, and the on_change
+// command of every changed slot. It tries every target and returns all
+// failures joined together.
+func (n *Notifier) Send(ctx context.Context, changes []Change) error {
+	if len(changes) == 0 {
+		return nil
+	}
+	var errs []error
+	if notify := n.Config.Notify; notify != nil {
+		for i, w := range notify.Webhooks {
+			for _, c := range changes {
+				if err := n.webhook(ctx, w, c); err != nil {
+					errs = append(errs, fmt.Errorf("notify.webhooks[%d] for %s: %w", i, c.path(), err))
+				}
+			}
+		}
+		if notify.Email != nil {
+			if err := n.email(ctx, notify.Email, changes); err != nil {
+				errs = append(errs, fmt.Errorf("notify.email %w", err))
+			}
+		}
+	}
+	for _, c := range changes {
+		_, _, slot, ok := n.Config.FindSlot(c.path())
+		if !ok || slot.OnChange == "" {
+			continue
+		}
+		if err := hook(ctx, slot.OnChangeShell, slot.OnChange, c); err != nil {
+			errs = append(errs, fmt.Errorf("on_change for %s: %w", c.path(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,209 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/halfdane/ilias/internal/config"
+	"github.com/halfdane/ilias/internal/runner"
+	"github.com/halfdane/ilias/internal/state"
+)
+
+var (
+	ok   = config.Status{ID: "ok", Label: "✅"}
+	down = config.Status{ID: "down", Label: "🔴"}
+)
+
+func dashboard(web, db config.Status) *runner.DashboardResult {
+	return &runner.DashboardResult{Groups: []runner.GroupResult{{
+		Name: "G",
+		Tiles: []runner.TileResult{{
+			Name: "T",
+			Slots: []runner.SlotResult{
+				{Name: "web", Status: web, Output: "HTTP 503 Service Unavailable"},
+				{Name: "db", Status: db},
+			},
+		}},
+	}}}
+}
+
+func TestChanges(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	if got := Changes(nil, dashboard(down, down), now); len(got) != 0 {
+		t.Errorf("first run: changes = %+v, want none", got)
+	}
+
+	prev := state.Statuses{"G/T/web": ok, "G/T/db": {ID: "down", Label: "old label"}}
+	got := Changes(prev, dashboard(down, down), now)
+	if len(got) != 1 {
+		t.Fatalf("changes = %+v, want only web", got)
+	}
+	want := Change{Group: "G", Tile: "T", Slot: "web", Old: ok, New: down, Output: "HTTP 503 Service Unavailable", Time: now}
+	if got[0] != want {
+		t.Errorf("change = %+v, want %+v", got[0], want)
+	}
+}
+
+func TestChanges_Maintenance(t *testing.T) {
+	prev := state.Statuses{"G/T/web": ok, "G/T/db": ok}
+	result := dashboard(runner.MaintenanceStatus, down)
+	result.Groups[0].Tiles[0].Slots[0].Maintenance = true
+
+	got := Changes(prev, result, time.Now())
+	if len(got) != 1 || got[0].Slot != "db" {
+		t.Errorf("changes = %+v, want only db", got)
+	}
+
+	saved := Statuses(prev, result)
+	if saved["G/T/web"] != ok || saved["G/T/db"] != down {
+		t.Errorf("saved statuses = %v, want web kept at ok and db down", saved)
+	}
+}
+
+func TestExcerpt(t *testing.T) {
+	long := strings.Repeat("é", maxExcerpt)
+	got := excerpt(long)
+	if len(got) > maxExcerpt+len("…") || !strings.HasSuffix(got, "…") {
+		t.Errorf("excerpt length %d, want at most %d plus ellipsis", len(got), maxExcerpt)
+	}
+	if !strings.HasPrefix(got, "éé") || strings.ContainsRune(got, '�') {
+		t.Errorf("excerpt split a character: %q", got[len(got)-8:])
+	}
+}
+
+func TestSend_Webhooks(t *testing.T) {
+	type request struct {
+		method, path, contentType, auth, body string
+	}
+	var got []request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got = append(got, request{r.Method, r.URL.Path, r.Header.Get("Content-Type"), r.Header.Get("Authorization"), string(body)})
+		if r.URL.Path == "/broken" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	cfg := &config.Config{Notify: &config.Notify{Webhooks: []config.Webhook{
+		{URL: srv.URL + "/json"},
+		{URL: srv.URL + "/broken"},
+		{
+			URL:     srv.URL + "/chat",
+			Method:  "PUT",
+			Headers: map[string]string{"Authorization": "Bearer t0ken"},
+			Body:    `{"text": {{json (printf "%s/%s: %s → %s" .Tile .Slot .Old.Label .New.Label)}}}`,
+		},
+	}}}
+	changes := []Change{{Group: "G", Tile: "T", Slot: "web", Old: ok, New: down, Output: "HTTP 503", Time: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}}
+
+	err := (&Notifier{Config: cfg}).Send(context.Background(), changes)
+	if err == nil || !strings.Contains(err.Error(), "notify.webhooks[1] for G/T/web: HTTP status 500") {
+		t.Errorf("err = %v, want the broken webhook reported", err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("got %d requests, want 3 (a failure doesn't stop the others)", len(got))
+	}
+
+	var p map[string]any
+	if err := json.Unmarshal([]byte(got[0].body), &p); err != nil {
+		t.Fatalf("default body is not JSON: %v\n%s", err, got[0].body)
+	}
+	if got[0].method != "POST" || got[0].contentType != "application/json" {
+		t.Errorf("default request = %s %s, want POST application/json", got[0].method, got[0].contentType)
+	}
+	if p["slot"] != "web" || p["old"].(map[string]any)["id"] != "ok" || p["new"].(map[string]any)["label"] != "🔴" ||
+		p["output"] != "HTTP 503" || p["time"] != "2024-05-01T12:00:00Z" {
+		t.Errorf("payload = %v", p)
+	}
+
+	chat := got[2]
+	if chat.method != "PUT" || chat.auth != "Bearer t0ken" || chat.body != `{"text": "T/web: ✅ → 🔴"}` {
+		t.Errorf("templated request = %+v", chat)
+	}
+}
+
+func TestSend_OnChange(t *testing.T) {
+	out := t.TempDir() + "/env"
+	cfg, err := config.Parse([]byte(`title: T
+on_change: 'echo "global $ILIAS_SLOT" >> ` + out + `'
+groups:
+  - name: G
+    tiles:
+      - name: T
+        on_change: 'echo "tile $ILIAS_GROUP/$ILIAS_TILE/$ILIAS_SLOT $ILIAS_OLD_STATUS $ILIAS_NEW_STATUS $ILIAS_OUTPUT" >> ` + out + `'
+        slots:
+          - { name: a, check: "true", rules: [{ match: {}, status: { id: ok, label: "✅" } }] }
+          - name: b
+            check: "true"
+            rules: [{ match: {}, status: { id: ok, label: "✅" } }]
+            on_change: "echo failing >&2; exit 3"
+      - name: U
+        slots:
+          - { name: c, check: "true", rules: [{ match: {}, status: { id: ok, label: "✅" } }] }
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes := []Change{
+		{Group: "G", Tile: "T", Slot: "a", Old: ok, New: down, Output: "it's down"},
+		{Group: "G", Tile: "T", Slot: "b", Old: ok, New: down},
+		{Group: "G", Tile: "U", Slot: "c", Old: down, New: ok},
+	}
+
+	err = (&Notifier{Config: cfg}).Send(context.Background(), changes)
+	if err == nil || !strings.Contains(err.Error(), "on_change for G/T/b: exit status 3: failing") {
+		t.Errorf("err = %v, want the failing hook reported with its output", err)
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(data), "tile G/T/a ok down it's down\nglobal c\n"; got != want {
+		t.Errorf("hooks wrote %q, want %q", got, want)
+	}
+}
+
+func TestSend_OnChangeShell(t *testing.T) {
+	out := t.TempDir() + "/shells"
+	cfg, err := config.Parse([]byte(`title: T
+defaults:
+  rules: [{ match: {}, status: { id: ok, label: "✅" } }]
+on_change: 'echo "$ILIAS_SLOT $0" >> ` + out + `'
+groups:
+  - name: G
+    tiles:
+      - name: T
+        defaults: { shell: sh }
+        slots: [{ name: a, check: "true" }]
+      - name: U
+        slots: [{ name: b, check: "true" }]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes := []Change{
+		{Group: "G", Tile: "T", Slot: "a", Old: ok, New: down},
+		{Group: "G", Tile: "U", Slot: "b", Old: ok, New: down},
+	}
+	if err := (&Notifier{Config: cfg}).Send(context.Background(), changes); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(data), "a sh\nb bash\n"; got != want {
+		t.Errorf("hooks wrote %q, want %q", got, want)
+	}
+}
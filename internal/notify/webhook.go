@@ -0,0 +1,90 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/halfdane/ilias/internal/config"
+)
+
+// defaultWebhookTimeout applies to webhooks without a timeout.
+const defaultWebhookTimeout = 10 * time.Second
+
+// payload is the default webhook body.
+type payload struct {
+	Group  string        `json:"group"`
+	Tile   string        `json:"tile"`
+	Slot   string        `json:"slot"`
+	Old    config.Status `json:"old"`
+	New    config.Status `json:"new"`
+	Output string        `json:"output"`
+	Time   time.Time     `json:"time"`
+}
+
+// webhook sends one change to w.
+func (n *Notifier) webhook(ctx context.Context, w config.Webhook, c Change) error {
+	var body string
+	if w.Body == "" {
+		data, err := json.Marshal(payload(c))
+		if err != nil {
+			return err
+		}
+		body = string(data)
+	} else {
+		tmpl, err := w.BodyTemplate()
+		if err != nil {
+			return err
+		}
+		var b strings.Builder
+		if err := tmpl.Execute(&b, c); err != nil {
+			return err
+		}
+		body = b.String()
+	}
+
+	timeout := w.Timeout.Duration
+	if timeout == 0 {
+		timeout = defaultWebhookTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	method := w.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+	req, err := http.NewRequestWithContext(ctx, method, w.URL, strings.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if w.Body == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range w.Headers {
+		if strings.EqualFold(k, "Host") {
+			req.Host = v
+			continue
+		}
+		req.Header.Set(k, v)
+	}
+
+	client := n.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("HTTP status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+// Package recording saves check results to disk and reads them back, so a
+// dashboard run can be reproduced without executing any checks.
+package recording
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/halfdane/ilias/internal/checker"
+)
+
+// Dir is a directory of recorded results, laid out as
+// <dir>/<group>/<tile>/<slot>.json with every name path-escaped.
+type Dir string
+
+// Save writes the result for a slot, creating directories as needed.
+func (d Dir) Save(group, tile, slot string, r checker.Result) error {
+	path := d.path(group, tile, slot)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("creating recording directory: %w", err)
+	}
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding result: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("writing recording: %w", err)
+	}
+	return nil
+}
+
+// Load reads the recorded result for a slot. It returns false if the slot has
+// no recording.
+func (d Dir) Load(group, tile, slot string) (checker.Result, bool, error) {
+	var r checker.Result
+	data, err := os.ReadFile(d.path(group, tile, slot))
+	if errors.Is(err, fs.ErrNotExist) {
+		return r, false, nil
+	}
+	if err != nil {
+		return r, false, fmt.Errorf("reading recording: %w", err)
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return r, false, fmt.Errorf("decoding recording %s: %w", d.path(group, tile, slot), err)
+	}
+	return r, true, nil
+}
+
+func (d Dir) path(group, tile, slot string) string {
+	return filepath.Join(string(d), escape(group), escape(tile), escape(slot)+".json")
+}
+
+// escape turns a name into a single safe path element. Slashes are escaped
+// by url.PathEscape; "." and ".." are escaped so they can't walk the tree.
+func escape(name string) string {
+	if name == "." || name == ".." {
+		return strings.ReplaceAll(name, ".", "%2E")
+	}
+	return url.PathEscape(name)
+}
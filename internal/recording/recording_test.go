@@ -0,0 +1,61 @@
+package recording
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/halfdane/ilias/internal/checker"
+)
+
+func TestSaveLoad_RoundTrip(t *testing.T) {
+	d := Dir(t.TempDir())
+	want := checker.Result{
+		Code:     -1,
+		Output:   "connection refused",
+		Err:      errors.New("performing request: connection refused"),
+		Duration: 1500 * time.Millisecond,
+	}
+
+	if err := d.Save("Network", "Disk (/home)", "usage", want); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	got, ok, err := d.Load("Network", "Disk (/home)", "usage")
+	if err != nil || !ok {
+		t.Fatalf("load: ok=%v err=%v", ok, err)
+	}
+	if got.Code != want.Code || got.Output != want.Output || got.Duration != want.Duration {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if got.Err == nil || got.Err.Error() != want.Err.Error() {
+		t.Errorf("err = %v, want %v", got.Err, want.Err)
+	}
+}
+
+func TestLoad_Missing(t *testing.T) {
+	_, ok, err := Dir(t.TempDir()).Load("G", "T", "s")
+	if err != nil || ok {
+		t.Errorf("ok=%v err=%v, want not found without error", ok, err)
+	}
+}
+
+func TestPath_StaysInsideDir(t *testing.T) {
+	root := t.TempDir()
+	d := Dir(root)
+	if err := d.Save("..", "a/../../b", ".", checker.Result{}); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	p := d.path("..", "a/../../b", ".")
+	rel, err := filepath.Rel(root, p)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		t.Errorf("path %q escapes %q", p, root)
+	}
+	if _, err := os.Stat(p); err != nil {
+		t.Errorf("recording not written at %q: %v", p, err)
+	}
+}
@@ -5,15 +5,19 @@ import (
 	"bytes"
 	"embed"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"html/template"
 	"io"
+	"io/fs"
 	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
+	"github.com/halfdane/ilias/internal/config"
 	"github.com/halfdane/ilias/internal/runner"
 )
 
@@ -23,14 +27,24 @@ var templateFS embed.FS
 //go:embed templates/style.css
 var embeddedCSS string
 
+// File names that a custom template directory may provide to override the
+// embedded defaults.
+const (
+	dashboardTemplate = "dashboard.tmpl"
+	styleSheet        = "style.css"
+)
+
 // templateData is the data structure passed to the HTML template.
+//
+// It is a stable contract for custom templates (see Options.TemplateDir):
+// fields may be added, but existing fields keep their names and meaning.
 type templateData struct {
-	Title          string
-	Theme          string
-	CSS            template.CSS
-	GeneratedAt    string
-	RefreshSeconds int
-	Version        string
+	Title          string       // dashboard title
+	Theme          string       // theme name, used as the data-theme attribute
+	CSS            template.CSS // the complete stylesheet, to be inlined
+	GeneratedAt    string       // "2006-01-02 15:04:05", or empty with --no-timestamp
+	RefreshSeconds int          // auto-refresh interval; 0 disables it
+	Version        string       // ilias version
 	Groups         []groupData
 }
 
@@ -50,10 +64,56 @@ type tileData struct {
 
 type slotData struct {
 	Name   string
+	Status string // status id, e.g. "ok"; useful as a CSS class
 	Label  string
 	Output string // raw check output, shown as tooltip
 }
 
+// funcMap holds the helper functions available to templates.
+var funcMap = template.FuncMap{
+	// firstChar returns the first character of s, or "?" if s is empty.
+	"firstChar": func(s string) string {
+		for _, r := range s {
+			return string(r)
+		}
+		return "?"
+	},
+	"lower":     strings.ToLower,
+	"upper":     strings.ToUpper,
+	"trim":      strings.TrimSpace,
+	"contains":  strings.Contains,
+	"hasPrefix": strings.HasPrefix,
+	"hasSuffix": strings.HasSuffix,
+	"replace":   strings.ReplaceAll,
+	"split":     strings.Split,
+	"join":      func(sep string, elems []string) string { return strings.Join(elems, sep) },
+	// truncate shortens s to at most n characters, appending "…" when cut.
+	"truncate": func(n int, s string) string {
+		r := []rune(s)
+		if len(r) <= n {
+			return s
+		}
+		return string(r[:n]) + "…"
+	},
+	// default returns def when s is empty.
+	"default": func(def, s string) string {
+		if s == "" {
+			return def
+		}
+		return s
+	},
+	// countStatus returns how many slots of a tile have the given status id.
+	"countStatus": func(id string, slots []slotData) int {
+		n := 0
+		for _, s := range slots {
+			if s.Status == id {
+				n++
+			}
+		}
+		return n
+	},
+}
+
 // Options configures HTML generation behaviour.
 type Options struct {
 	// NoTooltips suppresses check output from data-tooltip attributes.
@@ -67,6 +127,10 @@ type Options struct {
 	// GeneratedAt overrides the timestamp shown in the header.
 	// The zero value uses time.Now().
 	GeneratedAt time.Time
+	// TemplateDir is an optional directory whose dashboard.tmpl and
+	// style.css replace the embedded ones. Any other *.tmpl files in it are
+	// parsed as named templates that dashboard.tmpl can include.
+	TemplateDir string
 }
 
 // Render generates the HTML dashboard from the dashboard result.
@@ -75,34 +139,96 @@ type Options struct {
 // An optional Options value controls tooltip suppression and the generated-at
 // timestamp (useful for deterministic test output).
 func Render(result *runner.DashboardResult, configDir, version string, opts ...Options) ([]byte, error) {
-	css, err := loadCSS()
+	var o Options
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+
+	tmpl, css, err := load(o.TemplateDir)
+	if err != nil {
+		return nil, err
+	}
+	css, err = extendCSS(css, result, configDir)
 	if err != nil {
-		return nil, fmt.Errorf("loading CSS: %w", err)
+		return nil, err
 	}
 
-	funcMap := template.FuncMap{
-		"firstChar": func(s string) string {
-			for _, r := range s {
-				return string(r)
+	data := buildData(result, o, version, css)
+	for gi, g := range result.Groups {
+		for ti, t := range g.Tiles {
+			td := &data.Groups[gi].Tiles[ti]
+
+			// Resolve icon
+			if t.Icon != "" {
+				iconURI, err := resolveIcon(t.Icon, configDir)
+				if err != nil {
+					// Not fatal; just skip the icon
+					fmt.Fprintf(os.Stderr, "[warn] resolving icon for %q: %v\n", t.Name, err)
+					td.IconData = ""
+				} else {
+					td.IconData = template.URL(iconURI)
+				}
 			}
-			return "?"
-		},
+
+			// Resolve banner
+			if t.Banner != nil {
+				bannerURI, err := resolveIcon(t.Banner.Src, configDir)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "[warn] resolving banner for %q: %v\n", t.Name, err)
+				} else {
+					td.BannerURI = template.URL(bannerURI)
+				}
+			}
+		}
 	}
 
-	tmplData, err := templateFS.ReadFile("templates/dashboard.tmpl")
-	if err != nil {
-		return nil, fmt.Errorf("reading template: %w", err)
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("executing template: %w", err)
 	}
 
-	tmpl, err := template.New("dashboard").Funcs(funcMap).Parse(string(tmplData))
+	return buf.Bytes(), nil
+}
+
+// ValidateTemplate loads the templates and stylesheet from templateDir (or the
+// embedded ones when empty) and executes them against placeholder data built
+// from the config. No checks are run and no icons are fetched.
+func ValidateTemplate(cfg *config.Config, templateDir string) error {
+	tmpl, css, err := load(templateDir)
 	if err != nil {
-		return nil, fmt.Errorf("parsing template: %w", err)
+		return err
 	}
 
-	var o Options
-	if len(opts) > 0 {
-		o = opts[0]
+	result := &runner.DashboardResult{
+		Title:          cfg.Title,
+		Theme:          cfg.Theme,
+		RefreshSeconds: int(cfg.Refresh.Seconds()),
+		Groups:         make([]runner.GroupResult, len(cfg.Groups)),
 	}
+	for gi, g := range cfg.Groups {
+		result.Groups[gi] = runner.GroupResult{Name: g.Name, Tiles: make([]runner.TileResult, len(g.Tiles))}
+		for ti, t := range g.Tiles {
+			tr := runner.TileResult{Name: t.Name, Icon: t.Icon, Banner: t.Banner, Link: t.Link}
+			for _, s := range t.Slots {
+				tr.Slots = append(tr.Slots, runner.SlotResult{
+					Name:   s.Name,
+					Status: config.Status{ID: "validate", Label: "?"},
+					Output: "placeholder output",
+				})
+			}
+			result.Groups[gi].Tiles[ti] = tr
+		}
+	}
+
+	if err := tmpl.Execute(io.Discard, buildData(result, Options{}, "validate", css)); err != nil {
+		return fmt.Errorf("executing template: %w", err)
+	}
+	return nil
+}
+
+// buildData converts the run result into template data. Icons and banners are
+// left unresolved; HasIcon is set for tiles that configure one.
+func buildData(result *runner.DashboardResult, o Options, version, css string) templateData {
 	ts := o.GeneratedAt
 	if ts.IsZero() {
 		ts = time.Now()
@@ -130,31 +256,9 @@ func Render(result *runner.DashboardResult, configDir, version string, opts ...O
 		}
 		for ti, t := range g.Tiles {
 			td := tileData{
-				Name: t.Name,
-				Link: t.Link,
-			}
-
-			// Resolve icon
-			if t.Icon != "" {
-				td.HasIcon = true
-				iconURI, err := resolveIcon(t.Icon, configDir)
-				if err != nil {
-					// Not fatal; just skip the icon
-					fmt.Fprintf(os.Stderr, "[warn] resolving icon for %q: %v\n", t.Name, err)
-					td.IconData = ""
-				} else {
-					td.IconData = template.URL(iconURI)
-				}
-			}
-
-			// Resolve banner
-			if t.Banner != nil {
-				bannerURI, err := resolveIcon(t.Banner.Src, configDir)
-				if err != nil {
-					fmt.Fprintf(os.Stderr, "[warn] resolving banner for %q: %v\n", t.Name, err)
-				} else {
-					td.BannerURI = template.URL(bannerURI)
-				}
+				Name:    t.Name,
+				Link:    t.Link,
+				HasIcon: t.Icon != "",
 			}
 
 			td.Slots = make([]slotData, len(t.Slots))
@@ -165,6 +269,7 @@ func Render(result *runner.DashboardResult, configDir, version string, opts ...O
 				}
 				td.Slots[si] = slotData{
 					Name:   s.Name,
+					Status: s.Status.ID,
 					Label:  s.Status.Label,
 					Output: tooltipOutput,
 				}
@@ -173,17 +278,119 @@ func Render(result *runner.DashboardResult, configDir, version string, opts ...O
 		}
 		data.Groups[gi] = gd
 	}
+	return data
+}
 
-	var buf bytes.Buffer
-	if err := tmpl.Execute(&buf, data); err != nil {
-		return nil, fmt.Errorf("executing template: %w", err)
+// load returns the parsed dashboard template and the stylesheet, preferring
+// files from templateDir over the embedded defaults. templateDir itself must
+// exist; only the files missing from it fall back to the embedded ones.
+func load(templateDir string) (*template.Template, string, error) {
+	tmplData, err := templateFS.ReadFile("templates/" + dashboardTemplate)
+	if err != nil {
+		return nil, "", fmt.Errorf("reading template: %w", err)
 	}
+	css := embeddedCSS
 
-	return buf.Bytes(), nil
+	var partials []string
+	if templateDir != "" {
+		info, err := os.Stat(templateDir)
+		if err != nil {
+			return nil, "", fmt.Errorf("template directory: %w", err)
+		}
+		if !info.IsDir() {
+			return nil, "", fmt.Errorf("template directory: %s is not a directory", templateDir)
+		}
+		if data, ok, err := readOverride(templateDir, dashboardTemplate); err != nil {
+			return nil, "", err
+		} else if ok {
+			tmplData = data
+		}
+		if data, ok, err := readOverride(templateDir, styleSheet); err != nil {
+			return nil, "", err
+		} else if ok {
+			css = string(data)
+		}
+
+		partials, err = filepath.Glob(filepath.Join(templateDir, "*.tmpl"))
+		if err != nil {
+			return nil, "", fmt.Errorf("listing templates: %w", err)
+		}
+	}
+
+	tmpl, err := template.New("dashboard").Funcs(funcMap).Parse(string(tmplData))
+	if err != nil {
+		return nil, "", fmt.Errorf("parsing template: %w", err)
+	}
+
+	for _, path := range partials {
+		name := filepath.Base(path)
+		if name == dashboardTemplate {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, "", fmt.Errorf("reading template: %w", err)
+		}
+		if _, err := tmpl.New(name).Parse(string(data)); err != nil {
+			return nil, "", fmt.Errorf("parsing template %s: %w", name, err)
+		}
+	}
+
+	return tmpl, css, nil
+}
+
+// extendCSS appends the CSS variables of configured themes and the contents
+// of extra stylesheets to the base stylesheet, in that order, so user
+// stylesheets can override everything else.
+func extendCSS(css string, result *runner.DashboardResult, configDir string) (string, error) {
+	var b strings.Builder
+	b.WriteString(css)
+
+	names := make([]string, 0, len(result.Themes))
+	for name := range result.Themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		theme := result.Themes[name]
+		props := make([]string, 0, len(theme))
+		for prop := range theme {
+			props = append(props, prop)
+		}
+		sort.Strings(props)
+
+		fmt.Fprintf(&b, "\n[data-theme=%q] {\n", name)
+		for _, prop := range props {
+			fmt.Fprintf(&b, "  --%s: %s;\n", strings.TrimPrefix(prop, "--"), theme[prop])
+		}
+		b.WriteString("}\n")
+	}
+
+	for _, sheet := range result.Stylesheets {
+		path := sheet
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(configDir, path)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("reading stylesheet: %w", err)
+		}
+		fmt.Fprintf(&b, "\n/* %s */\n%s", filepath.Base(path), data)
+	}
+
+	return b.String(), nil
 }
 
-func loadCSS() (string, error) {
-	return embeddedCSS, nil
+// readOverride reads name from dir, returning false if it doesn't exist.
+func readOverride(dir, name string) ([]byte, bool, error) {
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("reading %s: %w", name, err)
+	}
+	return data, true, nil
 }
 
 // resolveIcon converts a display value to a data URI.
@@ -547,3 +547,172 @@ func TestRender_NoTooltips(t *testing.T) {
 		t.Error("expected tooltip content removed with NoTooltips=true")
 	}
 }
+
+// ---------------------------------------------------------------------------
+// custom template tests
+// ---------------------------------------------------------------------------
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRender_CustomTemplateDir(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"dashboard.tmpl": `<style>{{.CSS}}</style>{{range .Groups}}{{range .Tiles}}{{template "tile.tmpl" .}}{{end}}{{end}}`,
+		"tile.tmpl":      `<section class="{{lower .Name}}">{{range .Slots}}<i class="{{.Status}}">{{truncate 3 .Output}}</i>{{end}}</section>`,
+		"style.css":      `.custom { color: red; }`,
+	})
+
+	result := &runner.DashboardResult{
+		Title: "Test",
+		Theme: "dark",
+		Groups: []runner.GroupResult{{Name: "G", Tiles: []runner.TileResult{{
+			Name:  "Web",
+			Slots: []runner.SlotResult{{Name: "s", Status: config.Status{ID: "ok", Label: "✅"}, Output: "hello"}},
+		}}}},
+	}
+
+	html, err := Render(result, "/tmp", "test", Options{TemplateDir: dir})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := `<style>.custom { color: red; }</style><section class="web"><i class="ok">hel…</i></section>`
+	if string(html) != want {
+		t.Errorf("html = %q, want %q", html, want)
+	}
+}
+
+func TestRender_TemplateDirOnlyCSS(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"style.css": `.only-css {}`})
+
+	result := &runner.DashboardResult{Title: "Test", Theme: "dark"}
+	html, err := Render(result, "/tmp", "test", Options{TemplateDir: dir})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	output := string(html)
+	if !strings.Contains(output, ".only-css {}") {
+		t.Error("custom style.css not used")
+	}
+	if !strings.Contains(output, "<!DOCTYPE html>") {
+		t.Error("embedded dashboard.tmpl should still be used")
+	}
+	if strings.Contains(output, "--bg:") {
+		t.Error("embedded style.css should be replaced, not appended")
+	}
+}
+
+func TestRender_MissingTemplateDir(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "style.css")
+	writeFiles(t, filepath.Dir(file), map[string]string{"style.css": ""})
+
+	result := &runner.DashboardResult{Title: "Test", Theme: "dark"}
+	for dir, want := range map[string]string{
+		"/nonexistent/ilias-templates": "no such file or directory",
+		file:                           "is not a directory",
+	} {
+		_, err := Render(result, "/tmp", "test", Options{TemplateDir: dir})
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: err = %v, want to contain %q", dir, err, want)
+		}
+	}
+}
+
+func TestValidateTemplate(t *testing.T) {
+	cfg := &config.Config{
+		Title: "Test",
+		Groups: []config.Group{{Name: "G", Tiles: []config.Tile{{
+			Name:  "T",
+			Icon:  "https://example.invalid/icon.png", // must not be fetched
+			Slots: []config.Slot{{Name: "s"}},
+		}}}},
+	}
+
+	tests := []struct {
+		name    string
+		tmpl    string
+		wantErr string
+	}{
+		{name: "valid", tmpl: `{{range .Groups}}{{range .Tiles}}{{.Name}}{{range .Slots}}{{.Label}}{{end}}{{end}}{{end}}`},
+		{name: "parse error", tmpl: `{{range .Groups}}`, wantErr: "parsing template"},
+		{name: "unknown field", tmpl: `{{range .Groups}}{{.Nope}}{{end}}`, wantErr: "executing template"},
+		{name: "unknown function", tmpl: `{{shout .Title}}`, wantErr: "parsing template"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, map[string]string{"dashboard.tmpl": tt.tmpl})
+
+			err := ValidateTemplate(cfg, dir)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRender_ThemesAndStylesheets(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"extra.css": ".extra { color: hotpink; }"})
+
+	result := &runner.DashboardResult{
+		Title: "Test",
+		Theme: "solarized",
+		Themes: map[string]config.Theme{
+			"solarized": {"text": "#839496", "--bg": "#002b36"},
+		},
+		Stylesheets: []string{"extra.css"},
+	}
+
+	html, err := Render(result, dir, "test")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	output := string(html)
+
+	theme := "[data-theme=\"solarized\"] {\n  --bg: #002b36;\n  --text: #839496;\n}"
+	if !strings.Contains(output, theme) {
+		t.Errorf("output missing theme block %q", theme)
+	}
+	if !strings.Contains(output, `data-theme="solarized"`) {
+		t.Error("output missing data-theme attribute")
+	}
+	base := strings.Index(output, "--bg: #1a1b26")
+	themeAt := strings.Index(output, theme)
+	extra := strings.Index(output, ".extra { color: hotpink; }")
+	if base < 0 || extra < 0 || !(base < themeAt && themeAt < extra) {
+		t.Errorf("want built-in CSS, then themes, then stylesheets (got %d, %d, %d)", base, themeAt, extra)
+	}
+}
+
+func TestRender_MissingStylesheet(t *testing.T) {
+	result := &runner.DashboardResult{Title: "Test", Theme: "dark", Stylesheets: []string{"nope.css"}}
+	if _, err := Render(result, t.TempDir(), "test"); err == nil {
+		t.Error("expected error for missing stylesheet")
+	}
+}
+
+func TestRender_AutoThemeCSS(t *testing.T) {
+	html, err := Render(&runner.DashboardResult{Title: "Test", Theme: "auto"}, "/tmp", "test")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(string(html), `@media (prefers-color-scheme: light)`) {
+		t.Error("output missing prefers-color-scheme rule for the auto theme")
+	}
+}
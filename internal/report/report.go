@@ -0,0 +1,108 @@
+// Package report summarises dashboard results in the monitoring-plugin
+// (Nagios) format: a one-line status summary with perfdata and an exit code.
+package report
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/halfdane/ilias/internal/config"
+	"github.com/halfdane/ilias/internal/runner"
+)
+
+// maxProblems caps how many non-OK slots are listed in the summary line.
+const maxProblems = 5
+
+// SlotLevel is the plugin level of a single slot.
+type SlotLevel struct {
+	Path   string // "Group/Tile/slot"
+	Status config.Status
+	Level  config.Level
+	Result runner.SlotResult
+}
+
+// Summary is the aggregated plugin state of a dashboard run.
+type Summary struct {
+	Level config.Level // worst level across all slots
+	Slots []SlotLevel
+}
+
+// Summarize classifies every slot of the result using the given severity
+// mapping (nil means config.DefaultSeverity). Slots in maintenance or
+// unreachable are OK: the level of an unreachable slot's failed dependency
+// already counts.
+func Summarize(result *runner.DashboardResult, sev *config.Severity) Summary {
+	s := Summary{Level: config.LevelOK}
+	for _, g := range result.Groups {
+		for _, t := range g.Tiles {
+			for _, sr := range t.Slots {
+				level := sev.Level(sr.Status.ID)
+				if sr.Suppressed() {
+					level = config.LevelOK
+				}
+				s.Slots = append(s.Slots, SlotLevel{
+					Path:   config.SlotPath(g.Name, t.Name, sr.Name),
+					Status: sr.Status,
+					Level:  level,
+					Result: sr,
+				})
+				if level.Worse(s.Level) {
+					s.Level = level
+				}
+			}
+		}
+	}
+	return s
+}
+
+// ExitCode returns the plugin exit code for the summary.
+func (s Summary) ExitCode() int {
+	return int(s.Level)
+}
+
+// String formats the summary as a single plugin output line, e.g.
+//
+//	ILIAS CRITICAL - 1 critical, 3 ok: Network/Gateway/ping=down | 'Network/Gateway/ping'=0.012s;;;;
+func (s Summary) String() string {
+	counts := make(map[config.Level]int)
+	var problems []string
+	for _, sl := range s.Slots {
+		counts[sl.Level]++
+		if sl.Level != config.LevelOK {
+			problems = append(problems, fmt.Sprintf("%s=%s", sl.Path, sl.Status.ID))
+		}
+	}
+
+	var parts []string
+	for _, l := range []config.Level{config.LevelCritical, config.LevelUnknown, config.LevelWarning, config.LevelOK} {
+		if n := counts[l]; n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", n, strings.ToLower(l.String())))
+		}
+	}
+	if len(parts) == 0 {
+		parts = append(parts, "no slots")
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "ILIAS %s - %s", s.Level, strings.Join(parts, ", "))
+	if len(problems) > 0 {
+		if len(problems) > maxProblems {
+			problems = append(problems[:maxProblems], fmt.Sprintf("and %d more", len(problems)-maxProblems))
+		}
+		fmt.Fprintf(&b, ": %s", strings.Join(problems, ", "))
+	}
+
+	if len(s.Slots) > 0 {
+		b.WriteString(" |")
+		for _, sl := range s.Slots {
+			fmt.Fprintf(&b, " %s=%.3fs;;;;", perfLabel(sl.Path), sl.Result.Duration.Seconds())
+		}
+	}
+	return b.String()
+}
+
+// perfLabel quotes a perfdata label. Labels are always wrapped in single
+// quotes; embedded single quotes are doubled as the plugin guidelines require.
+func perfLabel(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
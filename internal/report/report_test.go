@@ -0,0 +1,105 @@
+package report
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/halfdane/ilias/internal/config"
+	"github.com/halfdane/ilias/internal/runner"
+)
+
+func dashboard(slots ...runner.SlotResult) *runner.DashboardResult {
+	return &runner.DashboardResult{
+		Title: "Test",
+		Groups: []runner.GroupResult{
+			{Name: "G", Tiles: []runner.TileResult{{Name: "T", Slots: slots}}},
+		},
+	}
+}
+
+func TestSummarize_AllOK(t *testing.T) {
+	s := Summarize(dashboard(
+		runner.SlotResult{Name: "a", Status: config.Status{ID: "ok"}, Duration: 12 * time.Millisecond},
+		runner.SlotResult{Name: "b", Status: config.Status{ID: "ok"}, Duration: 1500 * time.Millisecond},
+	), nil)
+
+	if s.Level != config.LevelOK {
+		t.Errorf("level = %v, want OK", s.Level)
+	}
+	if s.ExitCode() != 0 {
+		t.Errorf("exit code = %d, want 0", s.ExitCode())
+	}
+	want := "ILIAS OK - 2 ok | 'G/T/a'=0.012s;;;; 'G/T/b'=1.500s;;;;"
+	if got := s.String(); got != want {
+		t.Errorf("summary = %q, want %q", got, want)
+	}
+}
+
+func TestSummarize_WorstWins(t *testing.T) {
+	s := Summarize(dashboard(
+		runner.SlotResult{Name: "a", Status: config.Status{ID: "ok"}},
+		runner.SlotResult{Name: "b", Status: config.Status{ID: "warn"}},
+		runner.SlotResult{Name: "c", Status: config.Status{ID: "mystery"}},
+		runner.SlotResult{Name: "d", Status: config.Status{ID: "down"}},
+	), nil)
+
+	if s.Level != config.LevelCritical {
+		t.Errorf("level = %v, want CRITICAL", s.Level)
+	}
+	if s.ExitCode() != 2 {
+		t.Errorf("exit code = %d, want 2", s.ExitCode())
+	}
+	got := s.String()
+	if !strings.HasPrefix(got, "ILIAS CRITICAL - 1 critical, 1 unknown, 1 warning, 1 ok: G/T/b=warn, G/T/c=mystery, G/T/d=down |") {
+		t.Errorf("unexpected summary: %q", got)
+	}
+}
+
+func TestSummarize_CustomSeverity(t *testing.T) {
+	sev := &config.Severity{
+		OK:      []string{"green"},
+		Warning: []string{"amber"},
+	}
+	s := Summarize(dashboard(
+		runner.SlotResult{Name: "a", Status: config.Status{ID: "green"}},
+		runner.SlotResult{Name: "b", Status: config.Status{ID: "amber"}},
+		runner.SlotResult{Name: "c", Status: config.Status{ID: "ok"}},
+	), sev)
+
+	// "ok" is not listed in the custom mapping, so it is UNKNOWN.
+	if s.Level != config.LevelUnknown {
+		t.Errorf("level = %v, want UNKNOWN", s.Level)
+	}
+	if s.ExitCode() != 3 {
+		t.Errorf("exit code = %d, want 3", s.ExitCode())
+	}
+}
+
+func TestSummarize_Maintenance(t *testing.T) {
+	s := Summarize(dashboard(
+		runner.SlotResult{Name: "a", Status: config.Status{ID: "ok"}},
+		runner.SlotResult{Name: "b", Status: runner.MaintenanceStatus, Maintenance: true},
+		runner.SlotResult{Name: "c", Status: runner.UnreachableStatus, DependencyDown: "G/T/x"},
+	), nil)
+
+	if s.Level != config.LevelOK {
+		t.Errorf("level = %v, want OK", s.Level)
+	}
+	if got := s.String(); !strings.HasPrefix(got, "ILIAS OK - 3 ok |") {
+		t.Errorf("unexpected summary: %q", got)
+	}
+}
+
+func TestSummarize_NoSlots(t *testing.T) {
+	s := Summarize(dashboard(), nil)
+	if got, want := s.String(), "ILIAS OK - no slots"; got != want {
+		t.Errorf("summary = %q, want %q", got, want)
+	}
+}
+
+func TestPerfLabel_QuotesApostrophes(t *testing.T) {
+	if got, want := perfLabel("G/Bob's/s"), "'G/Bob''s/s'"; got != want {
+		t.Errorf("perfLabel = %q, want %q", got, want)
+	}
+}
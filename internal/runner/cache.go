@@ -0,0 +1,97 @@
+package runner
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/halfdane/ilias/internal/checker"
+	"github.com/halfdane/ilias/internal/config"
+	"github.com/halfdane/ilias/internal/state"
+)
+
+// cache reuses the results of checks with an interval across runs. A nil
+// *cache caches nothing.
+type cache struct {
+	dir    state.Dir
+	now    time.Time
+	logger *runLog
+
+	mu      sync.Mutex
+	results state.Results
+}
+
+// loadCache reads the cached results from the state directory. A cache that
+// cannot be read is reported as a warning and starts out empty.
+func loadCache(dir state.Dir, now time.Time, logger *runLog) *cache {
+	results, err := dir.Results()
+	if err != nil {
+		logger.warn("%v", err)
+	}
+	if results == nil {
+		results = make(state.Results)
+	}
+	return &cache{dir: dir, now: now, logger: logger, results: results}
+}
+
+// get returns the cached result for the slot at path, if it is younger than
+// the check's interval and was produced by the same check configuration.
+func (c *cache) get(path string, chk config.Check) (checker.Result, time.Time, bool) {
+	if c == nil || chk.Interval.Duration <= 0 {
+		return checker.Result{}, time.Time{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cr, ok := c.results[path]
+	if !ok || cr.Key != checkKey(chk) || c.now.Sub(cr.Time) >= chk.Interval.Duration {
+		return checker.Result{}, time.Time{}, false
+	}
+	return cr.Result, cr.Time, true
+}
+
+// put caches a fresh result for the slot at path.
+func (c *cache) put(path string, chk config.Check, r checker.Result) {
+	if c == nil || chk.Interval.Duration <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.results[path] = state.CachedResult{Key: checkKey(chk), Time: c.now, Result: r}
+}
+
+// save writes the cache back, dropping the results of slots that no longer
+// exist or no longer have an interval.
+func (c *cache) save(cfg *config.Config) {
+	if c == nil {
+		return
+	}
+	keep := make(state.Results)
+	for _, g := range cfg.Groups {
+		for _, t := range g.Tiles {
+			for _, s := range t.Slots {
+				path := config.SlotPath(g.Name, t.Name, s.Name)
+				if cr, ok := c.results[path]; ok && s.Check.Interval.Duration > 0 {
+					keep[path] = cr
+				}
+			}
+		}
+	}
+	if err := c.dir.SaveResults(keep); err != nil {
+		c.logger.warn("%v", err)
+	}
+}
+
+// checkKey identifies everything about a check that affects its result, so
+// that editing the check invalidates its cached result. Secrets in the
+// target or headers only enter the key as part of a hash.
+func checkKey(chk config.Check) string {
+	chk.Interval, chk.Pos = config.Duration{}, config.Pos{}
+	data, err := json.Marshal(chk)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,66 @@
+package runner
+
+import (
+	"fmt"
+
+	"github.com/halfdane/ilias/internal/config"
+)
+
+// UnreachableStatus replaces the status of slots whose check was skipped
+// because a slot they depend on is not OK.
+var UnreachableStatus = config.Status{ID: "unreachable", Label: "⚪"}
+
+// slotJob tracks a slot while Run schedules it. Slots wait for the jobs of
+// their dependencies to be done; config validation rules out cycles, so
+// this runs them in topological order.
+type slotJob struct {
+	done   chan struct{} // closed when status and ok are set
+	status config.Status
+	ok     bool // the evaluated status is OK, before any maintenance
+}
+
+// slotJobs returns a job per slot path. Like config.FindSlot, a path that is
+// not unique refers to the first slot.
+func slotJobs(cfg *config.Config) map[string]*slotJob {
+	jobs := make(map[string]*slotJob)
+	for _, g := range cfg.Groups {
+		for _, t := range g.Tiles {
+			for _, s := range t.Slots {
+				path := config.SlotPath(g.Name, t.Name, s.Name)
+				if _, ok := jobs[path]; !ok {
+					jobs[path] = &slotJob{done: make(chan struct{})}
+				}
+			}
+		}
+	}
+	return jobs
+}
+
+// waitDependencies waits until every dependency of slot is done and
+// returns the path and status of the first that is not OK.
+func waitDependencies(jobs map[string]*slotJob, slot config.Slot) (string, config.Status, bool) {
+	var path string
+	var status config.Status
+	for _, dep := range slot.DependsOn {
+		job, ok := jobs[dep]
+		if !ok {
+			continue
+		}
+		<-job.done
+		if !job.ok && path == "" {
+			path, status = dep, job.status
+		}
+	}
+	return path, status, path != ""
+}
+
+// unreachable is the result of a slot whose check was skipped because of
+// the dependency at path.
+func unreachable(slot config.Slot, path string, status config.Status) SlotResult {
+	return SlotResult{
+		Name:           slot.Name,
+		Status:         UnreachableStatus,
+		Output:         fmt.Sprintf("Dependency down: %s is %s %s", path, status.ID, status.Label),
+		DependencyDown: path,
+	}
+}
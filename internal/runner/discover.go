@@ -0,0 +1,109 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/halfdane/ilias/internal/checker"
+	"github.com/halfdane/ilias/internal/config"
+	"github.com/halfdane/ilias/internal/recording"
+)
+
+// discoverTile is the name of the tile that stands in for a group's
+// discovered tiles when discovery fails.
+const discoverTile = "Discovery failed"
+
+// discover runs the discover check of every group that has one and adds the
+// tiles it returns to cfg. Groups are discovered concurrently, limited by
+// sem; the tiles are added one group at a time.
+//
+// A failing discovery does not stop the run. The returned map holds the
+// (redacted) error per group index, for Run to show as an error tile.
+//
+// Discovery results are recorded and replayed like slots, under the group
+// with an empty tile name and the slot name "discover".
+func discover(ctx context.Context, cfg *config.Config, opts Options, logger *runLog, sem chan struct{}) map[int]error {
+	failed := make(map[int]error)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+
+	for gi := range cfg.Groups {
+		g := &cfg.Groups[gi]
+		if g.Discover == nil {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			result := discoverResult(ctx, opts, logger, cfg.Redact, g)
+			<-sem
+
+			mu.Lock()
+			defer mu.Unlock()
+			err := discoverError(g.Discover.Type, result)
+			if err == nil {
+				err = cfg.AddDiscovered(gi, []byte(discoverOutput(g.Discover.Type, result)))
+			}
+			if err != nil {
+				err = errors.New(cfg.Redact(err.Error()))
+				fmt.Fprintf(logger, "  [warn] discover %s: %v\n", g.Name, err)
+				failed[gi] = err
+			}
+		}()
+	}
+	wg.Wait()
+	return failed
+}
+
+// discoverResult runs (or replays) the discover check of a group.
+func discoverResult(ctx context.Context, opts Options, logger *runLog, redact func(string) string, g *config.Group) checker.Result {
+	if opts.Replay != "" {
+		fmt.Fprintf(logger, "  [replay] discover %s\n", g.Name)
+		return replaySlot(logger, opts.Replay, g.Name, "", "discover")
+	}
+
+	fmt.Fprintf(logger, "  [discover] %s: %s %s\n", g.Name, g.Discover.Type, redact(g.Discover.TargetString()))
+	var result checker.Result
+	if chk, err := checker.NewChecker(*g.Discover); err != nil {
+		result = checker.Result{Code: -1, Err: err}
+	} else {
+		result = chk.Check(ctx)
+	}
+
+	if opts.Record != "" {
+		if err := recording.Dir(opts.Record).Save(g.Name, "", "discover", redactResult(result, redact)); err != nil {
+			logger.warn("recording discover %s: %v", g.Name, err)
+		}
+	}
+	return result
+}
+
+// discoverOutput returns the tiles document of a successful discover check:
+// the output of a command, or the body of an HTTP response, without the
+// status line that HTTPChecker puts before it.
+func discoverOutput(typ string, r checker.Result) string {
+	if typ != "http" {
+		return r.Output
+	}
+	_, body, _ := strings.Cut(r.Output, "\n\n")
+	return body
+}
+
+// discoverError reports a discover check that did not succeed: one that
+// failed to run, a command with a non-zero exit code, or an HTTP status
+// other than 2xx.
+func discoverError(typ string, r checker.Result) error {
+	switch {
+	case r.Err != nil:
+		return r.Err
+	case typ == "http" && (r.Code < 200 || r.Code > 299):
+		return fmt.Errorf("HTTP status %d", r.Code)
+	case typ == "command" && r.Code != 0:
+		return fmt.Errorf("exit code %d", r.Code)
+	}
+	return nil
+}
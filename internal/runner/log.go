@@ -0,0 +1,25 @@
+package runner
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+// runLog is the logger of a run. It also collects the warnings for
+// DashboardResult.Warnings, since the logger may discard them.
+type runLog struct {
+	io.Writer
+
+	mu       sync.Mutex
+	warnings []string
+}
+
+// warn writes a warning to the logger and keeps it for the result.
+func (l *runLog) warn(format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	fmt.Fprintf(l, "  [warn] %s\n", msg)
+	l.mu.Lock()
+	l.warnings = append(l.warnings, msg)
+	l.mu.Unlock()
+}
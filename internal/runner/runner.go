@@ -2,10 +2,11 @@
 package runner
 
 import (
+	"cmp"
 	"context"
+	"errors"
 	"fmt"
 	"io"
-	"os/exec"
 	"runtime"
 	"strings"
 	"sync"
@@ -14,13 +15,43 @@ import (
 	"github.com/halfdane/ilias/internal/checker"
 	"github.com/halfdane/ilias/internal/config"
 	"github.com/halfdane/ilias/internal/evaluator"
+	"github.com/halfdane/ilias/internal/recording"
+	"github.com/halfdane/ilias/internal/state"
 )
 
+// MaintenanceStatus replaces the status of slots in a maintenance window or
+// silenced with "ilias silence".
+var MaintenanceStatus = config.Status{ID: "maintenance", Label: "🔧"}
+
+// TimedOutStatus replaces the status of slots whose check had not finished
+// when the run's context was done.
+var TimedOutStatus = config.Status{ID: "timeout", Label: "⏱️"}
+
 // SlotResult holds the evaluated status for a single slot.
 type SlotResult struct {
-	Name   string
-	Status config.Status
-	Output string // raw check output, for display on hover
+	Name     string
+	Status   config.Status
+	Output   string        // raw check output, for display on hover
+	Duration time.Duration // how long the check took
+
+	// Maintenance is set when Status is MaintenanceStatus: the slot is in
+	// a maintenance window or silenced.
+	Maintenance bool
+	// DependencyDown is the path of the dependency that was not OK when
+	// the check was skipped and Status set to UnreachableStatus.
+	DependencyDown string
+	// CachedAt is when the result was checked, if it was reused from the
+	// cache of a previous run rather than checked in this one.
+	CachedAt time.Time
+	// TimedOut is set when Status is TimedOutStatus: the run ended before
+	// the check finished.
+	TimedOut bool
+}
+
+// Suppressed reports whether the slot is in maintenance or unreachable.
+// Notifications and exit codes ignore such slots.
+func (s SlotResult) Suppressed() bool {
+	return s.Maintenance || s.DependencyDown != ""
 }
 
 // TileResult holds all the evaluated results for a single tile.
@@ -43,7 +74,13 @@ type DashboardResult struct {
 	Title          string
 	Theme          string
 	RefreshSeconds int // 0 means no auto-refresh
+	Themes         map[string]config.Theme
+	Stylesheets    []string // extra CSS files, relative to the config directory
 	Groups         []GroupResult
+
+	// Warnings are problems that did not stop the run, such as a result
+	// that could not be recorded. They are also written to the logger.
+	Warnings []string
 }
 
 // Options configures the runner behavior.
@@ -51,9 +88,39 @@ type Options struct {
 	Concurrency int
 	Verbose     bool
 	Logger      io.Writer // for verbose output, defaults to io.Discard
+
+	// Record, when non-empty, is a directory where every slot's check
+	// result is saved (see package recording).
+	Record string
+	// Replay, when non-empty, is a directory of recorded results that are
+	// used instead of executing the checks. Slots without a recording are
+	// reported as warnings and evaluated as failed checks.
+	Replay string
+
+	// StateDir, when non-empty, is the state directory whose silences
+	// (see package state) are applied to the slots. Unless replaying, it
+	// also caches the results of checks with an interval.
+	StateDir string
+	// Now is the time maintenance windows and silences are checked
+	// against; zero means time.Now().
+	Now time.Time
 }
 
 // Run executes all checks for the given config and returns the dashboard result.
+//
+// Groups with a discover check are discovered first; their tiles are added
+// to cfg before any check runs. A group whose discovery fails gets an extra
+// tile showing the error.
+//
+// Slots run once the slots they depend on are done. If one of those is not
+// OK, the check is skipped and the slot gets UnreachableStatus.
+//
+// Slots in a maintenance window or silenced are still checked, but get
+// MaintenanceStatus.
+//
+// When ctx is done (a deadline, or the user interrupted ilias), running
+// checks are killed and the ones not started yet are skipped. Run still
+// returns the full result, with those slots set to TimedOutStatus.
 func Run(ctx context.Context, cfg *config.Config, opts Options) (*DashboardResult, error) {
 	concurrency := opts.Concurrency
 	if concurrency <= 0 {
@@ -66,9 +133,9 @@ func Run(ctx context.Context, cfg *config.Config, opts Options) (*DashboardResul
 		}
 	}
 
-	logger := opts.Logger
-	if logger == nil {
-		logger = io.Discard
+	logger := &runLog{Writer: opts.Logger}
+	if logger.Writer == nil {
+		logger.Writer = io.Discard
 	}
 
 	sem := make(chan struct{}, concurrency)
@@ -77,9 +144,31 @@ func Run(ctx context.Context, cfg *config.Config, opts Options) (*DashboardResul
 		Title:          cfg.Title,
 		Theme:          cfg.Theme,
 		RefreshSeconds: int(cfg.Refresh.Seconds()),
+		Themes:         cfg.Themes,
+		Stylesheets:    cfg.CSS,
 		Groups:         make([]GroupResult, len(cfg.Groups)),
 	}
 
+	failed := discover(ctx, cfg, opts, logger, sem)
+
+	now := opts.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	var silences []state.Silence
+	var results *cache
+	if opts.StateDir != "" {
+		var err error
+		if silences, err = state.Dir(opts.StateDir).Silences(now); err != nil {
+			logger.warn("%v", err)
+		}
+		if opts.Replay == "" {
+			results = loadCache(state.Dir(opts.StateDir), now, logger)
+		}
+	}
+
+	jobs := slotJobs(cfg)
+	scheduled := make(map[*slotJob]bool)
 	var wg sync.WaitGroup
 	var mu sync.Mutex
 
@@ -88,6 +177,16 @@ func Run(ctx context.Context, cfg *config.Config, opts Options) (*DashboardResul
 			Name:  group.Name,
 			Tiles: make([]TileResult, len(group.Tiles)),
 		}
+		if err, ok := failed[gi]; ok {
+			result.Groups[gi].Tiles = append(result.Groups[gi].Tiles, TileResult{
+				Name: discoverTile,
+				Slots: []SlotResult{{
+					Name:   "discover",
+					Status: evaluator.BuiltinErrorStatus,
+					Output: err.Error(),
+				}},
+			})
+		}
 
 		for ti, tile := range group.Tiles {
 			result.Groups[gi].Tiles[ti] = TileResult{
@@ -108,7 +207,7 @@ func Run(ctx context.Context, cfg *config.Config, opts Options) (*DashboardResul
 					sem <- struct{}{}
 					defer func() { <-sem }()
 
-					if err := runGenerate(ctx, gen, logger, tileName); err != nil {
+					if err := runGenerate(ctx, gen, logger, cfg.Redact, tileName); err != nil {
 						fmt.Fprintf(logger, "  [warn] generate for %q failed: %v\n", tileName, err)
 					}
 				}()
@@ -118,13 +217,43 @@ func Run(ctx context.Context, cfg *config.Config, opts Options) (*DashboardResul
 				wg.Add(1)
 				gi, ti, si := gi, ti, si
 				slot := slot
-				tileName := tile.Name
+				groupName, tileName := group.Name, tile.Name
+				// Only the first slot of a duplicate path reports to its job.
+				job := jobs[config.SlotPath(groupName, tileName, slot.Name)]
+				if scheduled[job] {
+					job = nil
+				}
+				scheduled[job] = true
 				go func() {
 					defer wg.Done()
-					sem <- struct{}{}
-					defer func() { <-sem }()
 
-					sr := runSlot(ctx, slot, logger, tileName)
+					var sr SlotResult
+					dep, status, down := waitDependencies(jobs, slot)
+					switch {
+					case ctx.Err() != nil:
+						sr = timedOut(ctx, slot)
+					case down:
+						fmt.Fprintf(logger, "  [unreachable] %s/%s: %s is %s\n", tileName, slot.Name, dep, status.ID)
+						sr = unreachable(slot, dep, status)
+					default:
+						select {
+						case sem <- struct{}{}:
+							sr = runSlot(ctx, opts, logger, cfg.Redact, results, groupName, tileName, slot)
+							<-sem
+						case <-ctx.Done():
+							sr = timedOut(ctx, slot)
+						}
+					}
+					if job != nil {
+						job.status = sr.Status
+						job.ok = cfg.Severity.Level(sr.Status.ID) == config.LevelOK
+						close(job.done)
+					}
+
+					if reason, ok := maintenance(now, silences, group, tile, slot.Name); ok {
+						fmt.Fprintf(logger, "  [maintenance] %s/%s: %s\n", tileName, slot.Name, reason)
+						sr = inMaintenance(sr, reason)
+					}
 
 					mu.Lock()
 					result.Groups[gi].Tiles[ti].Slots[si] = sr
@@ -135,12 +264,16 @@ func Run(ctx context.Context, cfg *config.Config, opts Options) (*DashboardResul
 	}
 
 	wg.Wait()
+	results.save(cfg)
+	result.Warnings = logger.warnings
 
 	// Generate failures are warnings, not errors — the dashboard still renders
 	return result, nil
 }
 
-func runGenerate(ctx context.Context, gen *config.Generate, logger io.Writer, tileName string) error {
+// runGenerate runs the generate command of a tile. Its output is passed
+// through redact before it is logged.
+func runGenerate(ctx context.Context, gen *config.Generate, logger io.Writer, redact func(string) string, tileName string) error {
 	timeout := gen.Timeout.Duration
 	if timeout == 0 {
 		timeout = 60 * time.Second
@@ -149,42 +282,85 @@ func runGenerate(ctx context.Context, gen *config.Generate, logger io.Writer, ti
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
-	fmt.Fprintf(logger, "  [generate] %s: %s\n", tileName, gen.Command)
+	fmt.Fprintf(logger, "  [generate] %s: %s\n", tileName, gen.CommandString())
 
-	cmd := exec.CommandContext(ctx, "bash", "-c", "set -o pipefail; "+gen.Command)
+	cmd := checker.Shell(ctx, gen.Shell, gen.Command)
+	if len(gen.Args) > 0 {
+		cmd = checker.Exec(ctx, gen.Args)
+	}
+	checker.Setup(cmd, gen.Process)
 	output, err := cmd.CombinedOutput()
+	checker.KillLeftovers(cmd)
 	if len(output) > 0 {
-		fmt.Fprintf(logger, "  [generate-out] %s: %s\n", tileName, strings.TrimRight(string(output), "\n"))
+		fmt.Fprintf(logger, "  [generate-out] %s: %s\n", tileName, redact(strings.TrimRight(string(output), "\n")))
 	}
 	if err != nil {
-		return fmt.Errorf("command %q: %w", gen.Command, err)
+		return fmt.Errorf("command %q: %w", gen.CommandString(), err)
 	}
 	return nil
 }
 
-func runSlot(ctx context.Context, slot config.Slot, logger io.Writer, tileName string) SlotResult {
-	fmt.Fprintf(logger, "  [check] %s/%s: %s %s\n", tileName, slot.Name, slot.Check.Type, slot.Check.Target)
+// runSlot checks and evaluates a single slot. The rules see the check's
+// real output, but everything runSlot logs, records, caches or returns as
+// output is passed through redact first, so secrets never leave the
+// process. Cached and replayed results were stored redacted, and are
+// evaluated as such.
+//
+// Checks with an interval reuse a fresh enough result from c, and store
+// their result there otherwise.
+func runSlot(ctx context.Context, opts Options, logger *runLog, redact func(string) string, c *cache, groupName, tileName string, slot config.Slot) SlotResult {
+	path := config.SlotPath(groupName, tileName, slot.Name)
+	var result checker.Result
+	var cachedAt time.Time
+	fresh := false
+	if opts.Replay != "" {
+		result = replaySlot(logger, opts.Replay, groupName, tileName, slot.Name)
+		fmt.Fprintf(logger, "  [replay] %s/%s\n", tileName, slot.Name)
+	} else if cached, at, ok := c.get(path, slot.Check); ok {
+		result, cachedAt = cached, at
+		fmt.Fprintf(logger, "  [cached] %s/%s: from %s\n", tileName, slot.Name, at.Format(time.RFC3339))
+	} else {
+		fmt.Fprintf(logger, "  [check] %s/%s: %s %s\n", tileName, slot.Name, slot.Check.Type, redact(slot.Check.TargetString()))
+
+		chk, err := checker.NewChecker(slot.Check)
+		if err != nil {
+			fmt.Fprintf(logger, "  [error] %s/%s: %s\n", tileName, slot.Name, redact(err.Error()))
+			return SlotResult{Name: slot.Name, Status: evaluator.BuiltinErrorStatus}
+		}
+		result = chk.Check(ctx)
+		if ctx.Err() != nil {
+			fmt.Fprintf(logger, "  [timeout] %s/%s\n", tileName, slot.Name)
+			return timedOut(ctx, slot)
+		}
+		fresh = true
+	}
 
-	chk, err := checker.NewChecker(slot.Check.Type, slot.Check.Target, slot.Check.Timeout.Duration)
-	if err != nil {
-		fmt.Fprintf(logger, "  [error] %s/%s: %v\n", tileName, slot.Name, err)
-		return SlotResult{Name: slot.Name, Status: evaluator.BuiltinErrorStatus}
+	shown := redactResult(result, redact)
+	if fresh {
+		c.put(path, slot.Check, shown)
+	}
+	if opts.Record != "" {
+		if err := recording.Dir(opts.Record).Save(groupName, tileName, slot.Name, shown); err != nil {
+			logger.warn("recording %s: %v", path, err)
+		}
 	}
 
-	result := chk.Check(ctx)
-	if result.Err != nil {
-		fmt.Fprintf(logger, "  [warn] %s/%s: check error: %v\n", tileName, slot.Name, result.Err)
+	if shown.Err != nil {
+		fmt.Fprintf(logger, "  [warn] %s/%s: check error: %v\n", tileName, slot.Name, shown.Err)
 	}
 
-	output := result.Output
-	if result.Err != nil {
-		errMsg := result.Err.Error()
+	output := shown.Output
+	if shown.Err != nil {
+		errMsg := shown.Err.Error()
 		if output != "" {
 			output = output + "\n" + errMsg
 		} else {
 			output = errMsg
 		}
 	}
+	if !cachedAt.IsZero() {
+		output = strings.TrimSuffix("Cached result from "+cachedAt.Local().Format("2006-01-02 15:04:05")+"\n"+output, "\n")
+	}
 
 	status := evaluator.Evaluate(result, slot.Rules)
 	fmt.Fprintf(logger, "  [result] %s/%s: %s %s\n", tileName, slot.Name, status.ID, status.Label)
@@ -195,5 +371,69 @@ func runSlot(ctx context.Context, slot config.Slot, logger io.Writer, tileName s
 		output = output[:maxTooltipLen] + "\n... (truncated)"
 	}
 
-	return SlotResult{Name: slot.Name, Status: status, Output: output}
+	return SlotResult{Name: slot.Name, Status: status, Output: output, Duration: result.Duration, CachedAt: cachedAt}
+}
+
+// redactResult returns r with redact applied to its output and error.
+func redactResult(r checker.Result, redact func(string) string) checker.Result {
+	r.Output = redact(r.Output)
+	if r.Err != nil {
+		r.Err = errors.New(redact(r.Err.Error()))
+	}
+	return r
+}
+
+// timedOut is the result of a slot whose check did not finish before ctx
+// was done.
+func timedOut(ctx context.Context, slot config.Slot) SlotResult {
+	return SlotResult{
+		Name:     slot.Name,
+		Status:   TimedOutStatus,
+		Output:   fmt.Sprintf("The run ended before the check finished: %v", ctx.Err()),
+		TimedOut: true,
+	}
+}
+
+// maintenance reports whether a slot is in a maintenance window of its tile
+// or group, or silenced, and why.
+func maintenance(now time.Time, silences []state.Silence, g config.Group, t config.Tile, slot string) (string, bool) {
+	for _, windows := range [][]config.Maintenance{t.Maintenance, g.Maintenance} {
+		for i := range windows {
+			if windows[i].Active(now) {
+				return cmp.Or(windows[i].Reason, "maintenance window"), true
+			}
+		}
+	}
+	path := config.SlotPath(g.Name, t.Name, slot)
+	for _, s := range silences {
+		if s.Matches(path) {
+			return cmp.Or(s.Reason, "silenced") + " until " + s.Until.Local().Format("2006-01-02 15:04"), true
+		}
+	}
+	return "", false
+}
+
+// inMaintenance replaces the status of a slot result with
+// MaintenanceStatus. The check output is kept, after the reason.
+func inMaintenance(sr SlotResult, reason string) SlotResult {
+	sr.Status = MaintenanceStatus
+	sr.Maintenance = true
+	sr.Output = strings.TrimSuffix("Maintenance: "+reason+"\n"+sr.Output, "\n")
+	return sr
+}
+
+// replaySlot returns the recorded result for a slot. Missing or unreadable
+// recordings are reported as warnings and replayed as a failed check.
+func replaySlot(logger *runLog, dir, groupName, tileName, slotName string) checker.Result {
+	path := config.SlotPath(groupName, tileName, slotName)
+	result, ok, err := recording.Dir(dir).Load(groupName, tileName, slotName)
+	if err != nil {
+		logger.warn("replaying %s: %v", path, err)
+		return checker.Result{Code: -1, Err: err}
+	}
+	if !ok {
+		logger.warn("no recording for %s in %s", path, dir)
+		return checker.Result{Code: -1, Err: errors.New("no recording for this slot")}
+	}
+	return result
 }
@@ -3,10 +3,18 @@ package runner
 import (
 	"bytes"
 	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/halfdane/ilias/internal/config"
+	"github.com/halfdane/ilias/internal/recording"
+	"github.com/halfdane/ilias/internal/state"
 )
 
 func TestRun_BasicConfig(t *testing.T) {
@@ -222,4 +230,470 @@ func TestRun_WithGenerate(t *testing.T) {
 	}
 }
 
+func TestRunGenerate_Args(t *testing.T) {
+	noRedact := func(s string) string { return s }
+	dir := t.TempDir()
+	file := filepath.Join(dir, "it's here")
+	var buf bytes.Buffer
+	if err := runGenerate(context.Background(), &config.Generate{Args: []string{"touch", file}}, &buf, noRedact, "T"); err != nil {
+		t.Fatalf("runGenerate: %v", err)
+	}
+	if _, err := os.Stat(file); err != nil {
+		t.Errorf("the command list was not run as is: %v", err)
+	}
+
+	err := runGenerate(context.Background(), &config.Generate{Command: `test "$0" = sh`, Shell: "sh"}, &buf, noRedact, "T")
+	if err != nil {
+		t.Errorf("generate.shell was not used: %v", err)
+	}
+
+	gen := &config.Generate{
+		Command: `read -r in; echo "$in $OUT" > out`,
+		Process: config.Process{Env: map[string]string{"OUT": "env"}, Workdir: dir, Stdin: "stdin"},
+	}
+	if err := runGenerate(context.Background(), gen, &buf, noRedact, "T"); err != nil {
+		t.Fatalf("runGenerate: %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "out")); err != nil || string(data) != "stdin env\n" {
+		t.Errorf("out = %q, %v; want stdin and env, written to the workdir", data, err)
+	}
+}
+
+func TestRun_EvaluatesBeforeRedacting(t *testing.T) {
+	t.Setenv("ILIAS_TEST_HOST", "myhost")
+	cfg, err := config.Parse([]byte(`title: T
+groups:
+  - name: G
+    tiles:
+      - name: T
+        slots:
+          - name: s
+            check: 'echo "${secret:ILIAS_TEST_HOST} is healthy"'
+            rules:
+              - { match: { output: "^myhost is healthy" }, status: { id: ok, label: "✅" } }
+              - { match: {}, status: { id: down, label: "🔴" } }
+`))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	dir := t.TempDir()
+	result, err := Run(context.Background(), cfg, Options{Record: dir})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s := result.Groups[0].Tiles[0].Slots[0]; s.Status.ID != "ok" || s.Output != "*** is healthy" {
+		t.Errorf("slot = %+v, want ok, with the secret redacted from the output", s)
+	}
+	recorded, _, err := recording.Dir(dir).Load("G", "T", "s")
+	if err != nil || recorded.Output != "*** is healthy" {
+		t.Errorf("recorded = %+v, %v; want the secret redacted", recorded, err)
+	}
+}
+
+func TestRun_GenerateSecrets(t *testing.T) {
+	t.Setenv("ILIAS_TEST_TOKEN", "s3cr3t-token")
+	cfg, err := config.Parse([]byte(`title: T
+defaults:
+  rules: [{ match: {}, status: { id: ok, label: "✅" } }]
+groups:
+  - name: G
+    tiles:
+      - name: T
+        generate:
+          command: 'read -r in; echo "$TOKEN $in"'
+          env: { TOKEN: "${secret:ILIAS_TEST_TOKEN}" }
+          stdin: "from ${secret:ILIAS_TEST_TOKEN}"
+        slots: [{ name: up, check: "true" }]
+`))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	var log bytes.Buffer
+	if _, err := Run(context.Background(), cfg, Options{Logger: &log}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "[generate-out] T: *** from ***"; !strings.Contains(log.String(), want) {
+		t.Errorf("log = %q, want %q", log.String(), want)
+	}
+}
+
 func intPtr(i int) *int { return &i }
+
+func TestRun_RecordAndReplay(t *testing.T) {
+	slot := func(name, target string) config.Slot {
+		return config.Slot{
+			Name:  name,
+			Check: config.Check{Type: "command", Target: target},
+			Rules: []config.Rule{
+				{Match: config.Match{Code: &config.MatchValue{Exact: intPtr(0)}}, Status: config.Status{ID: "ok", Label: "✅"}},
+				{Match: config.Match{}, Status: config.Status{ID: "down", Label: "🔴"}},
+			},
+		}
+	}
+	cfg := &config.Config{
+		Title: "Test",
+		Groups: []config.Group{{
+			Name:  "G",
+			Tiles: []config.Tile{{Name: "T", Slots: []config.Slot{slot("a", "echo recorded")}}},
+		}},
+	}
+
+	dir := t.TempDir()
+	if _, err := Run(context.Background(), cfg, Options{Record: dir}); err != nil {
+		t.Fatalf("recording run: %v", err)
+	}
+
+	// Change the command so a live run would fail, and add a slot that was
+	// never recorded. Replay must use the recording for the first and
+	// evaluate the second as a failed check.
+	cfg.Groups[0].Tiles[0].Slots = []config.Slot{slot("a", "exit 1"), slot("b", "echo new")}
+
+	result, err := Run(context.Background(), cfg, Options{Replay: dir})
+	if err != nil {
+		t.Fatalf("replay run: %v", err)
+	}
+	slots := result.Groups[0].Tiles[0].Slots
+	if slots[0].Status.ID != "ok" || slots[0].Output != "recorded" {
+		t.Errorf("slot a = %+v, want recorded ok result", slots[0])
+	}
+	if slots[1].Status.ID != "down" {
+		t.Errorf("slot b status = %q, want %q (no recording)", slots[1].Status.ID, "down")
+	}
+	if want := "no recording for G/T/b in " + dir; len(result.Warnings) != 1 || result.Warnings[0] != want {
+		t.Errorf("warnings = %q, want [%q]", result.Warnings, want)
+	}
+}
+
+func TestRun_Discover(t *testing.T) {
+	cfg, err := config.Parse([]byte(`title: T
+defaults:
+  rules:
+    - match: { code: 0 }
+      status: { id: ok, label: "✅" }
+groups:
+  - name: Services
+    discover: "echo '[{ name: Web, slots: [{ name: up, check: \"true\" }] }]'"
+  - name: Broken
+    tiles: [{ name: Static, slots: [{ name: up, check: "true" }] }]
+    discover: "echo '[{ name: Bad, slots: [{ name: up }] }]'"
+  - name: Down
+    discover: "exit 3"
+`))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	result, err := Run(context.Background(), cfg, Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	services := result.Groups[0].Tiles
+	if len(services) != 1 || services[0].Name != "Web" || services[0].Slots[0].Status.ID != "ok" {
+		t.Errorf("services tiles = %+v, want discovered Web tile", services)
+	}
+
+	broken := result.Groups[1].Tiles
+	if len(broken) != 2 || broken[0].Name != "Static" || broken[1].Name != discoverTile {
+		t.Fatalf("broken tiles = %+v, want Static and an error tile", broken)
+	}
+	if s := broken[1].Slots[0]; s.Status.ID != "error" || !strings.Contains(s.Output, "check.target is required") {
+		t.Errorf("error slot = %+v, want validation error", s)
+	}
+
+	down := result.Groups[2].Tiles
+	if len(down) != 1 || !strings.Contains(down[0].Slots[0].Output, "exit code 3") {
+		t.Errorf("down tiles = %+v, want exit code error tile", down)
+	}
+}
+
+func TestRun_DiscoverURL(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer t0ken" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, "tiles:\n  - name: Web\n    slots: [{ name: up, check: \"true\" }]\n")
+	}))
+	defer srv.Close()
+
+	cfg, err := config.Parse([]byte(`title: T
+defaults:
+  rules:
+    - match: { code: 0 }
+      status: { id: ok, label: "✅" }
+groups:
+  - name: Services
+    discover: { target: "` + srv.URL + `", headers: { Authorization: "Bearer t0ken" } }
+`))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	result, err := Run(context.Background(), cfg, Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tiles := result.Groups[0].Tiles
+	if len(tiles) != 1 || tiles[0].Name != "Web" || tiles[0].Slots[0].Status.ID != "ok" {
+		t.Errorf("tiles = %+v, want the discovered Web tile", tiles)
+	}
+}
+
+func TestRun_Maintenance(t *testing.T) {
+	cfg, err := config.Parse([]byte(`title: T
+defaults:
+  rules:
+    - match: { code: 0 }
+      status: { id: ok, label: "✅" }
+    - match: {}
+      status: { id: down, label: "🔴" }
+groups:
+  - name: DB
+    maintenance:
+      - { schedule: "0 22 * * sun", duration: 2h, reason: backups }
+    tiles:
+      - name: Postgres
+        slots: [{ name: up, check: "echo dead; exit 1" }]
+  - name: Web
+    tiles:
+      - name: Nginx
+        maintenance: [{ from: "2024-05-01 10:00", until: "2024-05-01 11:00" }]
+        slots: [{ name: up, check: "true" }]
+      - name: API
+        slots: [{ name: up, check: "exit 1" }, { name: ready, check: "true" }]
+`))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	dir := state.Dir(t.TempDir())
+	// Sunday 2024-05-05, 23:30 local time.
+	now := time.Date(2024, 5, 5, 23, 30, 0, 0, time.Local)
+	if err := dir.AddSilence(state.Silence{Target: "Web/API/up", Until: now.Add(time.Hour), Reason: "deploy"}, now); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := Run(context.Background(), cfg, Options{StateDir: string(dir), Now: now})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	pg := result.Groups[0].Tiles[0].Slots[0]
+	if !pg.Maintenance || pg.Status != MaintenanceStatus || pg.Output != "Maintenance: backups\ndead" {
+		t.Errorf("postgres = %+v, want maintenance with reason and output", pg)
+	}
+	if nginx := result.Groups[1].Tiles[0].Slots[0]; nginx.Maintenance || nginx.Status.ID != "ok" {
+		t.Errorf("nginx = %+v, want ok (window is over)", nginx)
+	}
+	api := result.Groups[1].Tiles[1].Slots
+	if !api[0].Maintenance || !strings.Contains(api[0].Output, "deploy until 2024-05-06 00:30") {
+		t.Errorf("api/up = %+v, want silenced", api[0])
+	}
+	if api[1].Maintenance {
+		t.Errorf("api/ready = %+v, want not silenced", api[1])
+	}
+}
+
+func TestRun_UnreadableSilences(t *testing.T) {
+	cfg, err := config.Parse([]byte(`title: T
+defaults:
+  rules:
+    - match: { code: 0 }
+      status: { id: ok, label: "✅" }
+groups:
+  - name: G
+    tiles: [{ name: T, slots: [{ name: up, check: "true" }] }]
+`))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "silences.json"), []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var log strings.Builder
+	result, err := Run(context.Background(), cfg, Options{StateDir: dir, Logger: &log})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := result.Groups[0].Tiles[0].Slots[0]; got.Status.ID != "ok" {
+		t.Errorf("slot = %+v, want ok despite the unreadable silences", got)
+	}
+	if len(result.Warnings) != 1 || !strings.Contains(result.Warnings[0], "silences.json") {
+		t.Errorf("warnings = %q, want the unreadable silences", result.Warnings)
+	}
+	if !strings.Contains(log.String(), "  [warn] decoding state") {
+		t.Errorf("log = %q, want the warning", log.String())
+	}
+}
+
+func TestRun_DependsOn(t *testing.T) {
+	dir := t.TempDir()
+	cfg, err := config.Parse([]byte(`title: T
+defaults:
+  rules:
+    - match: { code: 0 }
+      status: { id: ok, label: "✅" }
+    - match: {}
+      status: { id: down, label: "🔴" }
+groups:
+  - name: Web
+    tiles:
+      - name: Blog
+        slots:
+          # Only passes if the dependency ran first.
+          - { name: http, check: "test -f ` + dir + `/gateway", depends_on: Network/Gateway/ping }
+          - { name: tls, check: "true", depends_on: Network/Router/ping }
+          - { name: cert, check: "true", depends_on: Web/Blog/tls }
+  - name: Network
+    tiles:
+      - name: Gateway
+        slots: [{ name: ping, check: "sleep 0.1; touch ` + dir + `/gateway" }]
+      - name: Router
+        slots: [{ name: ping, check: "echo unreachable; exit 1" }]
+`))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	result, err := Run(context.Background(), cfg, Options{Concurrency: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	blog := result.Groups[0].Tiles[0].Slots
+	if blog[0].Status.ID != "ok" {
+		t.Errorf("http = %+v, want ok after its dependency", blog[0])
+	}
+	if blog[1].Status != UnreachableStatus || blog[1].DependencyDown != "Network/Router/ping" || blog[1].Output != "Dependency down: Network/Router/ping is down 🔴" {
+		t.Errorf("tls = %+v, want unreachable because of the router", blog[1])
+	}
+	if blog[2].Status != UnreachableStatus || blog[2].DependencyDown != "Web/Blog/tls" {
+		t.Errorf("cert = %+v, want unreachable because of tls", blog[2])
+	}
+}
+
+func TestRun_Interval(t *testing.T) {
+	dir := t.TempDir()
+	counter := dir + "/runs"
+	parse := func(target string) *config.Config {
+		cfg, err := config.Parse([]byte(`title: T
+defaults:
+  rules: [{ match: {}, status: { id: ok, label: "✅" } }]
+groups:
+  - name: G
+    tiles:
+      - name: T
+        slots:
+          - { name: slow, check: { target: "` + target + `", interval: 1h } }
+          - { name: fast, check: "echo fast" }
+`))
+		if err != nil {
+			t.Fatalf("parse: %v", err)
+		}
+		return cfg
+	}
+	run := func(target string, now time.Time) SlotResult {
+		result, err := Run(context.Background(), parse(target), Options{StateDir: dir + "/state", Now: now})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return result.Groups[0].Tiles[0].Slots[0]
+	}
+
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.Local)
+	count := "echo x >> " + counter + "; grep -c x " + counter
+	if s := run(count, start); s.Output != "1" || !s.CachedAt.IsZero() {
+		t.Fatalf("first run = %+v, want a fresh check", s)
+	}
+	s := run(count, start.Add(59*time.Minute))
+	if !s.CachedAt.Equal(start) || s.Output != "Cached result from 2024-05-01 12:00:00\n1" {
+		t.Errorf("within the interval = %+v, want the cached result", s)
+	}
+	if s := run(count, start.Add(time.Hour)); s.Output != "2" {
+		t.Errorf("after the interval = %+v, want a fresh check", s)
+	}
+	if s := run(count+" # edited", start.Add(time.Hour+time.Minute)); s.Output != "3" {
+		t.Errorf("after editing the check = %+v, want a fresh check", s)
+	}
+}
+
+func TestRun_UnreadableCache(t *testing.T) {
+	cfg, err := config.Parse([]byte(`title: T
+defaults:
+  rules: [{ match: {}, status: { id: ok, label: "✅" } }]
+groups:
+  - name: G
+    tiles: [{ name: T, slots: [{ name: slow, check: { target: "echo fresh", interval: 1h } }] }]
+`))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "results.json"), []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var log strings.Builder
+	result, err := Run(context.Background(), cfg, Options{StateDir: dir, Logger: &log})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := result.Groups[0].Tiles[0].Slots[0]; got.Output != "fresh" {
+		t.Errorf("slot = %+v, want a fresh check", got)
+	}
+	if len(result.Warnings) != 1 || !strings.Contains(result.Warnings[0], "results.json") {
+		t.Errorf("warnings = %q, want the unreadable cache", result.Warnings)
+	}
+	if !strings.Contains(log.String(), "  [warn] decoding state") {
+		t.Errorf("log = %q, want the warning", log.String())
+	}
+}
+
+func TestRun_Cancelled(t *testing.T) {
+	cfg, err := config.Parse([]byte(`title: T
+defaults:
+  rules: [{ match: { code: 0 }, status: { id: ok, label: "✅" } }]
+groups:
+  - name: G
+    tiles:
+      - name: T
+        slots:
+          - { name: fast, check: "true" }
+          - { name: hung, check: "sleep 30" }
+          - { name: queued, check: "true" }
+`))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	// With one check at a time, the queued slot waits behind the hung one.
+	result, err := Run(ctx, cfg, Options{Concurrency: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if d := time.Since(start); d > 10*time.Second {
+		t.Errorf("run took %v, want it to end with the context", d)
+	}
+
+	slots := result.Groups[0].Tiles[0].Slots
+	timedOut := 0
+	for _, s := range slots {
+		if s.TimedOut {
+			timedOut++
+			if s.Status != TimedOutStatus || !strings.Contains(s.Output, "context deadline exceeded") {
+				t.Errorf("%s = %+v, want the timed out status", s.Name, s)
+			}
+		}
+	}
+	if !slots[1].TimedOut || timedOut < 1 {
+		t.Errorf("slots = %+v, want the hung slot timed out", slots)
+	}
+}
@@ -0,0 +1,168 @@
+// Package state keeps what ilias needs to remember between runs in a
+// directory: the status of every slot, the silences and the cached results
+// of checks with an interval.
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/halfdane/ilias/internal/checker"
+	"github.com/halfdane/ilias/internal/config"
+)
+
+// Dir is a state directory.
+type Dir string
+
+// DefaultDir returns $ILIAS_STATE_DIR if set, otherwise "ilias" below
+// $XDG_STATE_HOME (by default ~/.local/state).
+func DefaultDir() string {
+	if dir := os.Getenv("ILIAS_STATE_DIR"); dir != "" {
+		return dir
+	}
+	base := os.Getenv("XDG_STATE_HOME")
+	if base == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return filepath.Join(os.TempDir(), "ilias-state")
+		}
+		base = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(base, "ilias")
+}
+
+// Statuses maps slot paths (see config.SlotPath) to their status.
+type Statuses map[string]config.Status
+
+// Statuses reads the statuses saved by the previous run. It returns nil
+// without an error if there are none yet.
+func (d Dir) Statuses() (Statuses, error) {
+	var s Statuses
+	ok, err := d.read("statuses.json", &s)
+	if !ok {
+		return nil, err
+	}
+	return s, nil
+}
+
+// SaveStatuses replaces the saved statuses.
+func (d Dir) SaveStatuses(s Statuses) error {
+	return d.write("statuses.json", s)
+}
+
+// CachedResult is the result of a check with an interval.
+type CachedResult struct {
+	// Key identifies the check configuration the result is for; a result
+	// for a different key is stale.
+	Key    string         `json:"key"`
+	Time   time.Time      `json:"time"`
+	Result checker.Result `json:"result"`
+}
+
+// Results maps slot paths to their cached result.
+type Results map[string]CachedResult
+
+// Results reads the cached results. It returns nil without an error if
+// there are none yet.
+func (d Dir) Results() (Results, error) {
+	var r Results
+	if _, err := d.read("results.json", &r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// SaveResults replaces the cached results.
+func (d Dir) SaveResults(r Results) error {
+	return d.write("results.json", r)
+}
+
+// Silence mutes a group, tile or slot until it expires: its slots show the
+// maintenance status, like during a maintenance window.
+type Silence struct {
+	// Target is a "Group", "Group/Tile" or "Group/Tile/slot" path.
+	Target string    `json:"target"`
+	Until  time.Time `json:"until"`
+	Reason string    `json:"reason,omitempty"`
+}
+
+// Matches reports whether the silence covers the slot at path.
+func (s Silence) Matches(path string) bool {
+	return path == s.Target || strings.HasPrefix(path, s.Target+"/")
+}
+
+// Silences reads the silences that have not expired at now.
+func (d Dir) Silences(now time.Time) ([]Silence, error) {
+	var all []Silence
+	if _, err := d.read("silences.json", &all); err != nil {
+		return nil, err
+	}
+	var active []Silence
+	for _, s := range all {
+		if now.Before(s.Until) {
+			active = append(active, s)
+		}
+	}
+	return active, nil
+}
+
+// AddSilence saves a new silence, dropping expired ones.
+func (d Dir) AddSilence(s Silence, now time.Time) error {
+	active, err := d.Silences(now)
+	if err != nil {
+		return err
+	}
+	return d.write("silences.json", append(active, s))
+}
+
+// read decodes a JSON file of the state directory into v. It returns false
+// if the file does not exist.
+func (d Dir) read(name string, v any) (bool, error) {
+	path := filepath.Join(string(d), name)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("reading state: %w", err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return false, fmt.Errorf("decoding state %s: %w", path, err)
+	}
+	return true, nil
+}
+
+// write encodes v as JSON into a file of the state directory, creating the
+// directory as needed. The file is replaced atomically, so a crash never
+// leaves half a state behind.
+func (d Dir) write(name string, v any) error {
+	if err := os.MkdirAll(string(d), 0700); err != nil {
+		return fmt.Errorf("creating state directory: %w", err)
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding state: %w", err)
+	}
+	tmp, err := os.CreateTemp(string(d), name+".*")
+	if err != nil {
+		return fmt.Errorf("writing state: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing state: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing state: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(string(d), name)); err != nil {
+		return fmt.Errorf("writing state: %w", err)
+	}
+	return nil
+}
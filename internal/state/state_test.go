@@ -0,0 +1,103 @@
+package state
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/halfdane/ilias/internal/checker"
+)
+
+func TestStatuses_RoundTrip(t *testing.T) {
+	dir := Dir(filepath.Join(t.TempDir(), "nested", "state"))
+
+	s, err := dir.Statuses()
+	if err != nil || s != nil {
+		t.Fatalf("Statuses() before any run = %v, %v; want nil, nil", s, err)
+	}
+
+	if err := dir.SaveStatuses(Statuses{"G/T/s": {ID: "ok", Label: "✅"}}); err != nil {
+		t.Fatalf("SaveStatuses: %v", err)
+	}
+
+	s, err = dir.Statuses()
+	if err != nil {
+		t.Fatalf("Statuses: %v", err)
+	}
+	if got := s["G/T/s"]; got.ID != "ok" || got.Label != "✅" {
+		t.Errorf("G/T/s = %+v, want ok ✅", got)
+	}
+
+	entries, _ := os.ReadDir(string(dir))
+	if len(entries) != 1 {
+		t.Errorf("state dir has %d entries, want only statuses.json (no temp files)", len(entries))
+	}
+}
+
+func TestStatuses_Corrupt(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "statuses.json"), []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Dir(dir).Statuses(); err == nil {
+		t.Error("expected error for corrupt state")
+	}
+}
+
+func TestResults_RoundTrip(t *testing.T) {
+	dir := Dir(t.TempDir())
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	want := CachedResult{Key: "k", Time: at, Result: checker.Result{Code: 3, Output: "out", Err: errors.New("boom")}}
+	if err := dir.SaveResults(Results{"G/T/s": want}); err != nil {
+		t.Fatalf("SaveResults: %v", err)
+	}
+	r, err := dir.Results()
+	if err != nil {
+		t.Fatalf("Results: %v", err)
+	}
+	got := r["G/T/s"]
+	if got.Key != "k" || !got.Time.Equal(at) || got.Result.Code != 3 || got.Result.Output != "out" || got.Result.Err.Error() != "boom" {
+		t.Errorf("cached result = %+v, want %+v", got, want)
+	}
+}
+
+func TestSilences(t *testing.T) {
+	dir := Dir(t.TempDir())
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	if err := dir.AddSilence(Silence{Target: "G/T", Until: now.Add(time.Minute)}, now); err != nil {
+		t.Fatalf("AddSilence: %v", err)
+	}
+	later := now.Add(2 * time.Minute)
+	if err := dir.AddSilence(Silence{Target: "G", Until: later.Add(time.Hour), Reason: "upgrade"}, later); err != nil {
+		t.Fatalf("AddSilence: %v", err)
+	}
+
+	got, err := dir.Silences(later)
+	if err != nil {
+		t.Fatalf("Silences: %v", err)
+	}
+	if len(got) != 1 || got[0].Target != "G" || got[0].Reason != "upgrade" {
+		t.Fatalf("Silences() = %+v, want only the unexpired G silence", got)
+	}
+
+	for path, want := range map[string]bool{"G/T/s": true, "G": true, "Gx/T/s": false, "H/T/s": false} {
+		if got[0].Matches(path) != want {
+			t.Errorf("Matches(%q) = %v, want %v", path, !want, want)
+		}
+	}
+}
+
+func TestDefaultDir(t *testing.T) {
+	t.Setenv("ILIAS_STATE_DIR", "")
+	t.Setenv("XDG_STATE_HOME", "/xdg")
+	if got := DefaultDir(); got != "/xdg/ilias" {
+		t.Errorf("DefaultDir() = %q, want /xdg/ilias", got)
+	}
+	t.Setenv("ILIAS_STATE_DIR", "/var/lib/ilias")
+	if got := DefaultDir(); got != "/var/lib/ilias" {
+		t.Errorf("DefaultDir() = %q, want $ILIAS_STATE_DIR", got)
+	}
+}
@@ -98,6 +98,18 @@ in
       '';
     };
 
+    environmentFile = lib.mkOption {
+      type = lib.types.nullOr lib.types.path;
+      default = null;
+      example = "/run/secrets/ilias.env";
+      description = ''
+        File with `NAME=value` lines loaded into the service environment
+        (systemd `EnvironmentFile`). Use it for secrets referenced as
+        `''${secret:NAME}` in check targets, headers and bodies so they stay out
+        of the Nix store.
+      '';
+    };
+
     extraPackages = lib.mkOption {
       type = lib.types.listOf lib.types.package;
       default = [ ];
@@ -173,6 +185,9 @@ in
         # Give commands in checks access to the full NixOS system PATH.
         # Systemd's default PATH only covers /usr/bin:/bin which is empty on NixOS.
         Environment = "PATH=${lib.makeBinPath cfg.extraPackages}:/run/current-system/sw/bin:/run/wrappers/bin";
+        EnvironmentFile = lib.mkIf (cfg.environmentFile != null) cfg.environmentFile;
+        # Statuses of the previous run, for notifications.
+        StateDirectory = "ilias";
         ExecStart = lib.concatStringsSep " " ([
           "${cfg.package}/bin/ilias"
           "generate"
@@ -180,6 +195,7 @@ in
                 then "${cfg.configDir}/config.yaml"
                 else (toString cfg.configFile))
           "-o" cfg.outputPath
+          "--state-dir" "/var/lib/ilias"
         ] ++ lib.optional cfg.verbose "-v"
           ++ lib.optional cfg.noTooltips "--no-tooltips"
           ++ lib.optional cfg.noTimestamp "--no-timestamp");